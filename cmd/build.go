@@ -2,50 +2,153 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/gitzup/agent/internal/docker"
 	. "github.com/gitzup/agent/internal/logger"
 	"github.com/gitzup/agent/pkg/build"
+	"github.com/go-errors/errors"
 	"github.com/spf13/cobra"
 )
 
+// Output format of the build result; can be "text" or "json"
+var outputFormat string
+
 var buildCmd = &cobra.Command{
-	Use:   "build",
-	Short: "Process a build request.",
-	Long:  `This command will build the provided build request.`,
+	Use:     "build",
+	Short:   "Process a build request.",
+	Long:    `This command will build the provided build request.`,
+	PreRunE: validateOutputFormat,
 	Run: func(cmd *cobra.Command, args []string) {
-		Logger().Info(args)
-		if len(args) < 1 {
-			Logger().Fatal("build ID is required")
-		}
-		if len(args) < 2 {
-			Logger().Fatal("build file is required (use '-' for stdin)")
-		}
+		os.Exit(applyBuildRequest(args))
+	},
+}
+
+func init() {
+	addBuildFlags(buildCmd)
+	addOutputFlags(buildCmd)
+	rootCmd.AddCommand(buildCmd)
+}
 
-		id := args[0]
-		pipelineFile := args[1]
+// Applies the build request specified by the given command line arguments, and returns the exit code of the process;
+// exiting is left to the caller, so that deferred cleanups (eg. closing the state store) run first.
+func applyBuildRequest(args []string) int {
+	options := buildOptions(mustCreateRuntime())
+	options.StateStore = mustOpenStateStore()
+	defer closeStateStore(options.StateStore)
 
-		// TODO: handle pipeline file equaling "-"
-		bytes, err := ioutil.ReadFile(pipelineFile)
-		if err != nil {
-			Logger().WithError(err).Fatalf("failed reading '%s'", pipelineFile)
-		}
+	request, err := newBuildRequest(args, options)
+	if err != nil {
+		Logger().WithError(err).Error("failed creating build request")
+		return 1
+	}
 
-		request, err := build.New(id, workspacePath, bytes)
-		if err != nil {
-			Logger().WithError(err).Fatal("failed creating build request")
-		}
+	ctx, cancel := newSignalContext(context.WithValue(context.Background(), "request", request.Id()), "cancelling build request")
+	defer cancel()
+
+	result, err := request.Apply(ctx)
+	if printErr := printResult(os.Stdout, outputFormat, result); printErr != nil {
+		Logger().WithError(printErr).Error("failed printing build result")
+	}
+	if err != nil {
+		Logger().WithError(err).Error("failed applying build request")
+		return 1
+	}
+	return 0
+}
+
+// Registers the flags controlling how build results are printed on the given command.
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format of the build result (text, json)")
+}
+
+// Validates the output format flag.
+func validateOutputFormat(cmd *cobra.Command, args []string) error {
+	switch outputFormat {
+	case "text":
+	case "json":
+		// keep stdout clean for the JSON result
+		SetOutput(os.Stderr)
+	default:
+		return errors.Errorf("invalid output format: %s", outputFormat)
+	}
+	return nil
+}
 
-		// TODO: support timeout by using "context.WithTimeout(..)" as the context to "request.Apply(ctx)" method
-		err = request.Apply(context.WithValue(context.Background(), "request", request.Id()))
-		if err != nil {
-			Logger().WithError(err).Fatal("failed applying build request")
+// Returns a child of the given context which is cancelled when the process receives SIGINT or SIGTERM, so running
+// containers are stopped gracefully; the given action describes what cancelling it does. A second signal exits the
+// process immediately.
+func newSignalContext(parent context.Context, action string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			Logger().Warnf("Received %s; %s (send again to exit immediately)", sig, action)
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(signals)
+			return
 		}
+		sig := <-signals
+		Logger().Fatalf("Received %s again; exiting immediately", sig)
+	}()
+	return ctx, cancel
+}
 
-		// TODO: receive apply result and print it as text/json
-	},
+// Creates the Docker container runtime, exiting on failure.
+func mustCreateRuntime() build.Runtime {
+	config, err := runtimeConfig()
+	if err != nil {
+		Logger().WithError(err).Fatal("failed creating container runtime")
+	}
+	runtime, err := docker.NewRuntime(config)
+	if err != nil {
+		Logger().WithError(err).Fatal("failed creating container runtime")
+	}
+	return runtime
 }
 
-func init() {
-	rootCmd.AddCommand(buildCmd)
+// Opens the resource state store, exiting on failure.
+func mustOpenStateStore() build.StateStore {
+	stateStore, err := openStateStore()
+	if err != nil {
+		Logger().WithError(err).Fatal("failed opening state store")
+	}
+	return stateStore
+}
+
+// Closes the given resource state store (if any).
+func closeStateStore(stateStore build.StateStore) {
+	if stateStore != nil {
+		if err := stateStore.Close(); err != nil {
+			Logger().WithError(err).Warn("failed closing state store")
+		}
+	}
+}
+
+// Creates the build request specified by the given command line arguments (build ID and build file).
+func newBuildRequest(args []string, options build.Options) (build.Request, error) {
+	Logger().Info(args)
+	if len(args) < 1 {
+		return nil, errors.New("build ID is required")
+	}
+	if len(args) < 2 {
+		return nil, errors.New("build file is required (use '-' for stdin)")
+	}
+
+	id := args[0]
+	pipelineFile := args[1]
+
+	// TODO: handle pipeline file equaling "-"
+	bytes, err := ioutil.ReadFile(pipelineFile)
+	if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed reading '%s'", pipelineFile), 0)
+	}
+	return build.New(id, workspacePath, bytes, options)
 }
@@ -3,17 +3,52 @@ package cmd
 import (
 	"context"
 	"github.com/go-errors/errors"
+	"net/http"
+	"time"
 
 	"cloud.google.com/go/pubsub"
+	"github.com/gitzup/agent/internal/daemon"
 	. "github.com/gitzup/agent/internal/logger"
 	"github.com/gitzup/agent/pkg/build"
 	"github.com/spf13/cobra"
 )
 
+// Interval between sweeps removing stale containers, networks & workspaces; zero disables sweeping
+var gcInterval time.Duration
+
+// Pub/Sub topic to publish messages that could not be processed to; if empty, such messages are only logged
+var errorsTopicName string
+
+// Pub/Sub topic to publish build notifications & results to; if empty, no notifications are published
+var resultsTopicName string
+
+// Maximum number of attempts at processing a message before publishing it to the errors topic
+var maxAttempts int
+
+// Delay before a message that failed transiently is redelivered; doubles on each attempt, up to the maximum
+var minBackoff time.Duration
+var maxBackoff time.Duration
+
+// Maximum number of build requests to process concurrently
+var maxConcurrentBuilds int
+
+// Maximum number of messages held at once (being processed, waiting for a build slot, or backing off); zero means twice
+// the maximum number of concurrent builds
+var maxOutstandingMessages int
+
+// Address to serve metrics on (at "/debug/vars"); if empty, metrics are not served
+var metricsAddress string
+
+// Maximum duration to wait for builds in flight to finish when stopping, before cancelling them
+var drainTimeout time.Duration
+
+// Maximum duration for which a message's acknowledgement deadline is extended while its build request is processed
+var maxAckExtension time.Duration
+
 var daemonCmd = &cobra.Command{
 	Use:   "daemon",
 	Short: "Start the Gitzup agent daemon.",
-	Long:  `This command will start the Gitzup agent daemon, processing build request coming in through the GCP Pub/Sub subscription.`,
+	Long:  `This command will start the Gitzup agent daemon, processing build request coming in through the GCP Pub/Sub subscription. Messages that fail transiently are returned for redelivery after a backoff, which doubles on each attempt.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) < 1 {
 			return errors.New("GCP project ID is required")
@@ -27,13 +62,27 @@ var daemonCmd = &cobra.Command{
 }
 
 func init() {
+	addBuildFlags(daemonCmd)
+	addGCFlags(daemonCmd)
+	daemonCmd.Flags().DurationVar(&gcInterval, "gc-interval", time.Hour, "Interval between sweeps of stale containers, networks & workspaces (0 to disable)")
+	daemonCmd.Flags().StringVar(&errorsTopicName, "errors-topic", "", "Pub/Sub topic to publish messages that could not be processed to")
+	daemonCmd.Flags().StringVar(&resultsTopicName, "results-topic", "", "Pub/Sub topic to publish build notifications & results to")
+	daemonCmd.Flags().IntVar(&maxAttempts, "max-attempts", 5, "Maximum number of attempts at processing a message")
+	daemonCmd.Flags().DurationVar(&minBackoff, "min-backoff", 10*time.Second, "Delay before a failed message is redelivered (doubles on each attempt)")
+	daemonCmd.Flags().DurationVar(&maxBackoff, "max-backoff", 10*time.Minute, "Maximum delay before a failed message is redelivered")
+	daemonCmd.Flags().IntVar(&maxConcurrentBuilds, "max-concurrent-builds", 1, "Maximum number of build requests to process concurrently")
+	daemonCmd.Flags().IntVar(&maxOutstandingMessages, "max-outstanding-messages", 0, "Maximum number of messages to hold at once, including those waiting for a build slot or backing off (0 for twice the maximum number of concurrent builds)")
+	daemonCmd.Flags().StringVar(&metricsAddress, "metrics-address", "", "Address to serve metrics on, at '/debug/vars' (eg. ':8080')")
+	daemonCmd.Flags().DurationVar(&drainTimeout, "drain-timeout", 5*time.Minute, "Maximum duration to wait for builds in flight to finish when stopping, before cancelling them (and returning their messages for redelivery)")
+	daemonCmd.Flags().DurationVar(&maxAckExtension, "max-ack-extension", time.Hour, "Maximum duration to hold a message while processing it")
 	rootCmd.AddCommand(daemonCmd)
 }
 
 func startDaemon(gcpProject string, gcpSubscriptionName string) {
 
-	// Create context for the daemon
-	ctx := context.Background()
+	// Create context for the daemon; SIGINT & SIGTERM stop receiving messages, and drain builds in flight
+	ctx, cancel := newSignalContext(context.Background(), "stopping daemon")
+	defer cancel()
 
 	// Create the Pub/Sub client
 	client, err := pubsub.NewClient(ctx, gcpProject)
@@ -47,6 +96,17 @@ func startDaemon(gcpProject string, gcpSubscriptionName string) {
 		}
 	}()
 
+	// Create the container runtime & open the resource state store, shared by all build requests
+	runtime := mustCreateRuntime()
+	options := buildOptions(runtime)
+	options.StateStore = mustOpenStateStore()
+	defer closeStateStore(options.StateStore)
+
+	// Periodically remove leftovers of build requests interrupted by a crash
+	if gcInterval > 0 {
+		go sweepLoop(ctx, runtime, gcInterval)
+	}
+
 	// Locate the subscription, fail if missing
 	subscription := client.Subscription(gcpSubscriptionName)
 	exists, err := subscription.Exists(ctx)
@@ -56,42 +116,81 @@ func startDaemon(gcpProject string, gcpSubscriptionName string) {
 		Logger().WithError(err).Fatalf("Could not find subscription '%s'", subscription)
 	}
 
+	// Locate the errors topic (if any), fail if missing
+	var errorsTopic *pubsub.Topic
+	if errorsTopicName != "" {
+		errorsTopic = mustFindTopic(ctx, client, errorsTopicName)
+		defer errorsTopic.Stop()
+	}
+
+	// Locate the results topic (if any), fail if missing
+	var resultsTopic *pubsub.Topic
+	if resultsTopicName != "" {
+		resultsTopic = mustFindTopic(ctx, client, resultsTopicName)
+		defer resultsTopic.Stop()
+	}
+
 	// Start receiving messages (in separate goroutines)
-	Logger().Infof("Subscribing to: %s", subscription)
-	err = subscription.Receive(ctx, func(_ context.Context, msg *pubsub.Message) { handleMessage(msg) })
+	d, err := daemon.New(daemon.Config{
+		Subscription:           subscription,
+		ErrorsTopic:            errorsTopic,
+		ResultsTopic:           resultsTopic,
+		MaxAttempts:            maxAttempts,
+		MinBackoff:             minBackoff,
+		MaxBackoff:             maxBackoff,
+		MaxConcurrentBuilds:    maxConcurrentBuilds,
+		MaxOutstandingMessages: maxOutstandingMessages,
+		DrainTimeout:           drainTimeout,
+		MaxExtension:           maxAckExtension,
+		WorkspacePath:          workspacePath,
+		Options:                options,
+	})
 	if err != nil {
+		Logger().WithError(err).Fatal("Could not create daemon")
+	}
+
+	// Serve metrics (registered by the daemon via expvar), if requested
+	if metricsAddress != "" {
+		go serveMetrics(metricsAddress)
+	}
+	if err := d.Run(ctx); err != nil {
 		Logger().WithError(err).Fatalf("Could not subscribe to '%s'", subscription)
 	}
 }
 
-func handleMessage(msg *pubsub.Message) {
-	defer func() {
-		err := recover()
-		if err != nil {
-			// TODO: re-publish this message to the errors topic?
-			switch t := err.(type) {
-			case error:
-				// TODO: print with stacktrace
-				Logger().WithError(t).Errorf("Failed processing message '%s'", msg.ID)
-			default:
-				// TODO: print with stacktrace
-				Logger().Errorf("Failed processing message '%s': %#v", msg.ID, t)
-			}
-		}
-	}()
-
-	msg.Ack()
-
-	request, err := build.New(msg.ID, workspacePath, msg.Data)
+// Returns the given Pub/Sub topic, exiting if it does not exist.
+func mustFindTopic(ctx context.Context, client *pubsub.Client, name string) *pubsub.Topic {
+	topic := client.Topic(name)
+	exists, err := topic.Exists(ctx)
 	if err != nil {
-		panic(err)
+		Logger().WithError(err).Fatalf("Failed verifying that topic '%s' exists", name)
+	} else if exists == false {
+		Logger().Fatalf("Could not find topic '%s'", name)
 	}
+	return topic
+}
 
-	// TODO: timeout support should be provided as metadata on the pub/sub message
-	err = request.Apply(context.WithValue(context.Background(), "request", request.Id()))
-	if err != nil {
-		panic(err)
+// Serves metrics at "/debug/vars" on the given address, exiting if that fails.
+func serveMetrics(address string) {
+	Logger().Infof("Serving metrics on: %s", address)
+	if err := http.ListenAndServe(address, nil); err != nil {
+		Logger().WithError(err).Fatalf("Could not serve metrics on '%s'", address)
 	}
+}
 
-	// TODO: receive apply result, and send Pub/Sub message with JSON of result
+// Removes stale containers, networks & workspaces once immediately, and then at the given interval until the context is
+// done.
+func sweepLoop(ctx context.Context, runtime build.Runtime, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := build.CollectGarbage(ctx, gcOptions(runtime)); err != nil {
+			Logger().WithError(err).Warn("Garbage collection sweep failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
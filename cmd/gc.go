@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/spf13/cobra"
+)
+
+// Minimum age of containers, networks & workspaces for garbage collection to remove them
+var gcMaxAge time.Duration
+
+// Whether to collect leftovers of all agent instances, rather than just this instance's
+var gcAllInstances bool
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove stale containers, networks & workspaces.",
+	Long:  `This command will remove containers, networks & workspaces left behind by build requests (eg. if the agent crashed mid-build).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runtime := mustCreateRuntime()
+		result, err := build.CollectGarbage(context.Background(), gcOptions(runtime))
+		if result != nil {
+			Logger().Infof("Removed %d containers, %d networks & %d workspaces", len(result.Containers), len(result.Networks), len(result.Workspaces))
+		}
+		if err != nil {
+			Logger().WithError(err).Fatal("garbage collection failed")
+		}
+	},
+}
+
+func init() {
+	addGCFlags(gcCmd)
+	rootCmd.AddCommand(gcCmd)
+}
+
+// Registers the flags controlling garbage collection on the given command.
+func addGCFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&gcMaxAge, "gc-max-age", 24*time.Hour, "Minimum age of stale containers, networks & workspaces to remove")
+	cmd.Flags().BoolVar(&gcAllInstances, "gc-all-instances", false, "Remove stale leftovers of all agent instances, not just this one")
+}
+
+// Returns the garbage collection options, as configured by the command line flags.
+func gcOptions(runtime build.Runtime) build.GCOptions {
+	options := build.GCOptions{
+		Runtime:       runtime,
+		WorkspacePath: workspacePath,
+		MaxAge:        gcMaxAge,
+		Instance:      instance,
+	}
+	if gcAllInstances {
+		options.Instance = ""
+	}
+	return options
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/go-errors/errors"
+)
+
+// Prints the given build result to the given writer, in the given format ("text" or "json").
+func printResult(w io.Writer, format string, result *build.Result) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case "text":
+		if result.Mode == build.ModePlan {
+			return printPlanText(w, result)
+		}
+		return printResultText(w, result)
+	default:
+		return errors.Errorf("unsupported output format: %s", format)
+	}
+}
+
+func printResultText(w io.Writer, result *build.Result) error {
+	names := make([]string, 0, len(result.Resources))
+	for name := range result.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Build request '%s' %s (took %s)\n\n", result.RequestId, result.Status, formatDurationMs(result.DurationMs))
+
+	// resources summary table
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "RESOURCE\tTYPE\tSTATUS\tCHANGES\tDURATION")
+	for _, name := range names {
+		res := result.Resources[name]
+		var durationMs int64
+		for _, phase := range res.Phases {
+			durationMs += phase.DurationMs
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\n", name, res.Type, res.Status, len(res.Changes), formatDurationMs(durationMs))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	// changes of each resource
+	for _, name := range names {
+		res := result.Resources[name]
+		if len(res.Changes) > 0 {
+			fmt.Fprintf(w, "\n%s:\n", name)
+			for _, change := range res.Changes {
+				fmt.Fprintf(w, "  %s\n", change)
+			}
+		}
+	}
+
+	// warnings reported by actions
+	printWarnings(w, names, result)
+
+	// errors
+	if result.Error != nil {
+		fmt.Fprintln(w, "\nErrors:")
+		for _, name := range names {
+			if err := result.Resources[name].Error; err != nil {
+				fmt.Fprintf(w, "  %s (%s): %s\n", name, err.Phase, err.Message)
+			}
+		}
+	}
+	return nil
+}
+
+func printPlanText(w io.Writer, result *build.Result) error {
+	names := make([]string, 0, len(result.Resources))
+	for name := range result.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// count resources by planned action
+	creates, updates, unchanged := 0, 0, 0
+	for _, name := range names {
+		switch plannedAction(result.Resources[name]) {
+		case "create":
+			creates++
+		case "update":
+			updates++
+		case "no-op":
+			unchanged++
+		}
+	}
+	fmt.Fprintf(w, "Plan for build request '%s' %s: %d to create, %d to update, %d unchanged\n",
+		result.RequestId, result.Status, creates, updates, unchanged)
+
+	// field-level changes of each resource
+	for _, name := range names {
+		res := result.Resources[name]
+		switch plannedAction(res) {
+		case "create":
+			fmt.Fprintf(w, "\n+ %s (%s) will be created\n", name, res.Type)
+			for _, change := range res.Changes {
+				config, ok := change.Desired.(map[string]interface{})
+				if change.Op != build.ChangeOpCreate || !ok {
+					continue
+				}
+				keys := make([]string, 0, len(config))
+				for key := range config {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					field := build.Change{Op: build.ChangeOpAdd, Path: "/" + key, Desired: config[key]}
+					fmt.Fprintf(w, "    %s\n", formatPlannedChange(res, field))
+				}
+			}
+		case "update":
+			fmt.Fprintf(w, "\n~ %s (%s) will be updated\n", name, res.Type)
+			for _, change := range res.Changes {
+				fmt.Fprintf(w, "    %s\n", formatPlannedChange(res, change))
+			}
+		case "no-op":
+			fmt.Fprintf(w, "\n  %s (%s) is up-to-date\n", name, res.Type)
+		default:
+			fmt.Fprintf(w, "\n! %s (%s) %s\n", name, res.Type, res.Status)
+			if res.Error != nil {
+				fmt.Fprintf(w, "    %s (%s)\n", res.Error.Message, res.Error.Phase)
+			}
+		}
+	}
+	printWarnings(w, names, result)
+	return nil
+}
+
+// Returns the textual representation of the given planned change of the given resource. Desired values that are only
+// known once the changes of other resources are applied are shown as such, rather than as unresolved references.
+func formatPlannedChange(res *build.ResourceResult, change build.Change) string {
+	if !res.IsKnownAfterApply(change.Path) {
+		return change.String()
+	}
+	switch change.Op {
+	case build.ChangeOpAdd:
+		return fmt.Sprintf("add %s: (known after apply)", change.Path)
+	case build.ChangeOpCreate:
+		return change.String()
+	default:
+		current, err := json.Marshal(change.Current)
+		if err != nil {
+			return change.String()
+		}
+		return fmt.Sprintf("update %s: %s => (known after apply)", change.Path, current)
+	}
+}
+
+// Prints the warning events reported by the actions of the given resources (if any).
+func printWarnings(w io.Writer, names []string, result *build.Result) {
+	header := false
+	for _, name := range names {
+		for _, event := range result.Resources[name].Events {
+			if event.Type != build.EventWarning {
+				continue
+			}
+			if !header {
+				fmt.Fprintln(w, "\nWarnings:")
+				header = true
+			}
+			fmt.Fprintf(w, "  %s (%s): %s\n", name, event.Action, event.Message)
+		}
+	}
+}
+
+// Returns the action that applying the given planned resource would take: "create", "update", or "no-op". Returns an
+// empty string if the resource was not planned successfully.
+func plannedAction(res *build.ResourceResult) string {
+	switch res.Status {
+	case build.ResourceStatusUpToDate:
+		return "no-op"
+	case build.ResourceStatusPending:
+		for _, change := range res.Changes {
+			if change.Op == build.ChangeOpCreate {
+				return "create"
+			}
+		}
+		return "update"
+	default:
+		return ""
+	}
+}
+
+func formatDurationMs(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/spf13/cobra"
+)
+
+// Exit code of the "plan" command when the build request has pending changes.
+const exitCodePendingChanges = 2
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Preview the changes a build request would apply.",
+	Long: `This command will initialize the resources of the provided build request and discover their current state,
+and print the changes that building the request would apply, without applying them.
+
+Exits with code 0 if no changes are pending, 2 if changes are pending, and 1 on failure.`,
+	PreRunE: validateOutputFormat,
+	Run: func(cmd *cobra.Command, args []string) {
+		os.Exit(planBuildRequest(args))
+	},
+}
+
+func init() {
+	addBuildFlags(planCmd)
+	addOutputFlags(planCmd)
+	rootCmd.AddCommand(planCmd)
+}
+
+// Plans the build request specified by the given command line arguments, and returns the exit code of the process;
+// exiting is left to the caller, so that deferred cleanups (eg. closing the state store) run first.
+func planBuildRequest(args []string) int {
+	options := buildOptions(mustCreateRuntime())
+	options.StateStore = mustOpenStateStore()
+	defer closeStateStore(options.StateStore)
+
+	request, err := newBuildRequest(args, options)
+	if err != nil {
+		Logger().WithError(err).Error("failed creating build request")
+		return 1
+	}
+
+	ctx, cancel := newSignalContext(context.WithValue(context.Background(), "request", request.Id()), "cancelling build request")
+	defer cancel()
+
+	result, err := request.Plan(ctx)
+	if printErr := printResult(os.Stdout, outputFormat, result); printErr != nil {
+		Logger().WithError(printErr).Error("failed printing build plan")
+	}
+	if err != nil {
+		Logger().WithError(err).Error("failed planning build request")
+		return 1
+	} else if result.HasPendingChanges() {
+		return exitCodePendingChanges
+	}
+	return 0
+}
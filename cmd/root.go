@@ -1,13 +1,81 @@
 package cmd
 
 import (
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gitzup/agent/internal/docker"
 	. "github.com/gitzup/agent/internal/logger"
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/go-errors/errors"
 	"github.com/spf13/cobra"
 )
 
 // Workspace to place all build request workspaces in
 var workspacePath string
 
+// Identifier of this agent instance, recorded on the containers & networks it creates (defaults to the hostname)
+var instance string
+
+// Maximum number of resources to process concurrently within a single build request
+var parallelism int
+
+// Maximum number of resources of a given type to process concurrently (across all build requests of the agent), each in
+// "<type>=<limit>" format
+var resourceTypeLimits []string
+
+// Whether to abort a build request as soon as any of its resources fails, or let independent resources finish first
+var failFast bool
+
+// Maximum duration of a whole build request; zero means no limit
+var buildTimeout time.Duration
+
+// Default maximum duration of each resource action, unless overridden by the resource or the build request
+var actionTimeout time.Duration
+
+// User (and optionally group) to run action containers as, in "<user>[:<group>]" format (eg. "1000:1000"); files written
+// by actions to their workspace are owned by this user. If empty, the image's default user is used.
+var containerUser string
+
+// Explicit registry credentials, each in "<registry>=<username>:<password>" format
+var registryAuth []string
+
+// JSON file mapping registry hostnames to credentials (eg. '{"gcr.io": {"username": "...", "password": "..."}}')
+var registryCredentialsFile string
+
+// Directory containing Docker's "config.json" file, used to resolve registry credentials (including credential helpers)
+var dockerConfigDir string
+
+// Policy determining when action images are pulled; can be "Always", "IfNotPresent", "Never", or empty to pull
+// untagged & "latest" images always, and other images only if not present
+var pullPolicy string
+
+// Default resource limits of action containers (memory may use a binary unit suffix, eg. "512m"); zero means no limit
+var cpuLimit float64
+var memoryLimit string
+var pidsLimit int64
+
+// Maximum resource limits resources may request for their action containers; zero means no maximum
+var maxCPULimit float64
+var maxMemoryLimit string
+var maxPIDsLimit int64
+
+// Number of last output lines of a failed action to include in its error
+var outputTailLines int
+
+// Maximum size of each artifact handed back by an action, and of all artifacts of a single action ("0" for no limit)
+var maxArtifactSize string
+var maxArtifactsSize string
+
+// Type of store to persist resource state in across build requests; can be "none", "file" or "bolt":
+//  * "none": resource state is not persisted
+//  * "file": each resource state record is kept as a JSON file under "<workspace>/.state"
+//  * "bolt": resource state records are kept in an embedded BoltDB database at "<workspace>/.state.db"
+var stateStoreType string
+
 // Log output format; can be "auto", "json", "plain" or "pretty":
 //  * "auto": if a TTY is attached, acts the same as "pretty"; otherwise uses "json"
 //  * "json": each log entry will be a JSON object containing all available information such as msg, timestamp, etc
@@ -39,11 +107,166 @@ var rootCmd = &cobra.Command{
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&workspacePath, "workspace", "w", ".", "Workspace location")
+	rootCmd.PersistentFlags().StringVar(&instance, "instance", defaultInstance(), "Identifier of this agent instance")
 	rootCmd.PersistentFlags().StringVar(&logFormat, "logformat", "auto", "Log output format (auto, json, plain, pretty)")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "loglevel", "info", "Log level (trace, debug, info, warn, error, fatal, panic)")
 	rootCmd.PersistentFlags().BoolVarP(&caller, "caller", "c", false, "Include caller information in log output")
 }
 
+// Registers the flags controlling how build requests are processed on the given command.
+func addBuildFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&parallelism, "parallelism", "p", 1, "Maximum number of resources to process concurrently")
+	cmd.Flags().StringArrayVar(&resourceTypeLimits, "resource-type-limit", nil, "Maximum number of resources of a type to process concurrently, in '<type>=<limit>' format (repeatable)")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", true, "Abort the build on the first resource failure")
+	cmd.Flags().StringVar(&stateStoreType, "state-store", "file", "Resource state store (none, file, bolt)")
+	cmd.Flags().StringVar(&containerUser, "container-user", "", "User to run action containers as, in '<user>[:<group>]' format (defaults to the image's user)")
+	cmd.Flags().StringVar(&pullPolicy, "pull", "", "Image pull policy (Always, IfNotPresent, Never); by default, only untagged & 'latest' images are always pulled")
+	cmd.Flags().StringArrayVar(&registryAuth, "registry-auth", nil, "Registry credentials in '<registry>=<username>:<password>' format (repeatable)")
+	cmd.Flags().StringVar(&registryCredentialsFile, "registry-credentials-file", "", "JSON file mapping registry hostnames to credentials")
+	cmd.Flags().StringVar(&dockerConfigDir, "docker-config", defaultDockerConfigDir(), "Docker configuration directory to resolve registry credentials from (empty to disable)")
+	cmd.Flags().Float64Var(&cpuLimit, "cpus", 1, "Default number of CPUs of action containers (0 for no limit)")
+	cmd.Flags().StringVar(&memoryLimit, "memory", "1g", "Default memory limit of action containers, eg. '512m' (0 for no limit)")
+	cmd.Flags().Int64Var(&pidsLimit, "pids-limit", 1024, "Default maximum number of processes in action containers (0 for no limit)")
+	cmd.Flags().Float64Var(&maxCPULimit, "max-cpus", 0, "Maximum number of CPUs resources may request (0 for no maximum)")
+	cmd.Flags().StringVar(&maxMemoryLimit, "max-memory", "0", "Maximum memory limit resources may request (0 for no maximum)")
+	cmd.Flags().Int64Var(&maxPIDsLimit, "max-pids-limit", 0, "Maximum number of processes resources may request (0 for no maximum)")
+	cmd.Flags().IntVar(&outputTailLines, "output-tail-lines", 20, "Number of last output lines of a failed action to include in its error")
+	cmd.Flags().StringVar(&maxArtifactSize, "max-artifact-size", "64m", "Maximum size of each artifact handed back by an action (0 for no limit)")
+	cmd.Flags().StringVar(&maxArtifactsSize, "max-artifacts-size", "256m", "Maximum total size of the artifacts handed back by an action (0 for no limit)")
+	cmd.Flags().DurationVar(&buildTimeout, "timeout", 0, "Maximum duration of the whole build request (0 for no limit)")
+	cmd.Flags().DurationVar(&actionTimeout, "action-timeout", 10*time.Minute, "Default maximum duration of each resource action")
+}
+
+// Returns the default agent instance identifier, which is the hostname.
+func defaultInstance() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
+// Returns Docker's default configuration directory: "$DOCKER_CONFIG" if set, or "~/.docker" otherwise.
+func defaultDockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	} else if home := os.Getenv("HOME"); home != "" {
+		return path.Join(home, ".docker")
+	}
+	return ""
+}
+
+// Returns the Docker container runtime configuration, as configured by the command line flags.
+func runtimeConfig() (docker.Config, error) {
+	credentials := make(map[string]docker.RegistryCredentials)
+	for _, auth := range registryAuth {
+		tokens := strings.SplitN(auth, "=", 2)
+		if len(tokens) != 2 || tokens[0] == "" {
+			return docker.Config{}, errors.New("invalid registry credentials (expected '<registry>=<username>:<password>')")
+		}
+		userAndPassword := strings.SplitN(tokens[1], ":", 2)
+		if len(userAndPassword) != 2 {
+			return docker.Config{}, errors.Errorf("invalid registry credentials for '%s' (expected '<registry>=<username>:<password>')", tokens[0])
+		}
+		credentials[tokens[0]] = docker.RegistryCredentials{Username: userAndPassword[0], Password: userAndPassword[1]}
+	}
+	return docker.Config{
+		RegistryAuth: docker.RegistryAuthConfig{
+			Credentials:     credentials,
+			CredentialsFile: registryCredentialsFile,
+			DockerConfigDir: dockerConfigDir,
+		},
+	}, nil
+}
+
+// Opens the resource state store, as configured by the command line flags. Returns nil if state persistence is
+// disabled.
+func openStateStore() (build.StateStore, error) {
+	switch stateStoreType {
+	case "none":
+		return nil, nil
+	case "file":
+		return build.NewFileStateStore(path.Join(workspacePath, ".state"))
+	case "bolt":
+		return build.NewBoltStateStore(path.Join(workspacePath, ".state.db"))
+	default:
+		return nil, errors.Errorf("invalid state store: %s", stateStoreType)
+	}
+}
+
+// Returns the default & maximum action container limits, as configured by the command line flags.
+func containerLimits() (build.Limits, build.Limits, error) {
+	memory, err := build.ParseMemory(memoryLimit)
+	if err != nil {
+		return build.Limits{}, build.Limits{}, errors.WrapPrefix(err, "invalid memory limit", 0)
+	}
+	maxMemory, err := build.ParseMemory(maxMemoryLimit)
+	if err != nil {
+		return build.Limits{}, build.Limits{}, errors.WrapPrefix(err, "invalid maximum memory limit", 0)
+	}
+	limits := build.Limits{CPUs: cpuLimit, Memory: memory, PIDs: pidsLimit}
+	maxLimits := build.Limits{CPUs: maxCPULimit, Memory: maxMemory, PIDs: maxPIDsLimit}
+	if _, err := limits.Enforce(maxLimits); err != nil {
+		return build.Limits{}, build.Limits{}, errors.WrapPrefix(err, "invalid default limits", 0)
+	}
+	return limits, maxLimits, nil
+}
+
+// Returns the limiter of concurrently processed resources of each type, as configured by the command line flags.
+func typeLimiter() (*build.TypeLimiter, error) {
+	limits := make(map[string]int)
+	for _, limit := range resourceTypeLimits {
+		equals := strings.LastIndex(limit, "=")
+		if equals <= 0 {
+			return nil, errors.Errorf("invalid resource type limit '%s' (expected '<type>=<limit>')", limit)
+		}
+		value, err := strconv.Atoi(limit[equals+1:])
+		if err != nil {
+			return nil, errors.Errorf("invalid resource type limit '%s' (expected '<type>=<limit>')", limit)
+		}
+		limits[limit[:equals]] = value
+	}
+	return build.NewTypeLimiter(limits)
+}
+
+// Returns the build request options, as configured by the command line flags; the resource state store (if any) is left
+// for the caller to open & set, since it must be closed.
+func buildOptions(runtime build.Runtime) build.Options {
+	limits, maxLimits, err := containerLimits()
+	if err != nil {
+		Logger().WithError(err).Fatal("invalid container limits")
+	}
+	limiter, err := typeLimiter()
+	if err != nil {
+		Logger().WithError(err).Fatal("invalid resource type limits")
+	}
+	artifactSize, err := build.ParseMemory(maxArtifactSize)
+	if err != nil {
+		Logger().WithError(err).Fatal("invalid maximum artifact size")
+	}
+	artifactsSize, err := build.ParseMemory(maxArtifactsSize)
+	if err != nil {
+		Logger().WithError(err).Fatal("invalid maximum artifacts size")
+	}
+
+	options := build.DefaultOptions()
+	options.Runtime = runtime
+	options.Instance = instance
+	options.Parallelism = parallelism
+	options.TypeLimiter = limiter
+	options.FailFast = failFast
+	options.PullPolicy = build.PullPolicy(pullPolicy)
+	options.ContainerUser = containerUser
+	options.Limits = limits
+	options.MaxLimits = maxLimits
+	options.OutputTailLines = outputTailLines
+	options.MaxArtifactSize = artifactSize
+	options.MaxArtifactsSize = artifactsSize
+	options.Timeout = buildTimeout
+	options.ActionTimeout = actionTimeout
+	return options
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
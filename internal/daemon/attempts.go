@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/go-errors/errors"
+)
+
+// Duration after which a message that was not seen again is forgotten by the attempt counter (eg. because it was
+// redelivered to another agent).
+const attemptsExpiry = 24 * time.Hour
+
+// Maximum number of messages tracked by the attempt counter; beyond it, the least recently seen messages are forgotten.
+const maxTrackedMessages = 10000
+
+// Counts the attempts made at processing each message. Attempts are persisted to a file (see attemptsPath), so they
+// survive restarts of the agent (eg. when it crashes while processing a message); attempts made by other agents that
+// the message was redelivered to are not counted.
+type attemptCounter struct {
+	mutex    sync.Mutex
+	path     string
+	attempts map[string]*messageAttempts
+}
+
+type messageAttempts struct {
+	Count    int       `json:"count"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Returns the path of the file persisting the attempts counted by the given agent instance, under the given workspace
+// directory. The file name is dot-prefixed, so it never collides with build request workspaces.
+func attemptsPath(workspacePath string, instance string) string {
+	if instance == "" {
+		return filepath.Join(workspacePath, ".attempts.json")
+	}
+	return filepath.Join(workspacePath, fmt.Sprintf(".attempts-%s.json", instance))
+}
+
+// Creates an attempt counter persisted to the given file, loading the attempts it already contains (if it exists).
+func newAttemptCounter(path string) (*attemptCounter, error) {
+	counter := &attemptCounter{path: path, attempts: make(map[string]*messageAttempts)}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed creating directory of '%s'", path), 0)
+	}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return counter, nil
+	} else if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed reading message attempts from '%s'", path), 0)
+	}
+	if err := json.Unmarshal(b, &counter.attempts); err != nil {
+		Logger().WithError(err).Warnf("Ignoring corrupt message attempts file '%s'", path)
+		counter.attempts = make(map[string]*messageAttempts)
+	}
+	return counter, nil
+}
+
+// Records & returns the number of the current attempt at processing the given message.
+func (counter *attemptCounter) increment(messageID string) int {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	now := time.Now()
+	counter.prune(now)
+	attempts, ok := counter.attempts[messageID]
+	if !ok {
+		attempts = &messageAttempts{}
+		counter.attempts[messageID] = attempts
+	}
+	attempts.Count++
+	attempts.LastSeen = now
+	counter.save()
+	return attempts.Count
+}
+
+// Forgets the attempts made at processing the given message.
+func (counter *attemptCounter) forget(messageID string) {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	if _, ok := counter.attempts[messageID]; ok {
+		delete(counter.attempts, messageID)
+		counter.save()
+	}
+}
+
+// Forgets expired messages, and the least recently seen messages beyond the maximum number of tracked messages (making
+// room for one more). Must be called with the mutex held.
+func (counter *attemptCounter) prune(now time.Time) {
+	for messageID, attempts := range counter.attempts {
+		if now.Sub(attempts.LastSeen) > attemptsExpiry {
+			delete(counter.attempts, messageID)
+		}
+	}
+	for len(counter.attempts) >= maxTrackedMessages {
+		var oldestID string
+		var oldest time.Time
+		for messageID, attempts := range counter.attempts {
+			if oldestID == "" || attempts.LastSeen.Before(oldest) {
+				oldestID, oldest = messageID, attempts.LastSeen
+			}
+		}
+		delete(counter.attempts, oldestID)
+	}
+}
+
+// Persists the counted attempts; failures are only logged, since attempts are still counted in memory. Must be called
+// with the mutex held.
+func (counter *attemptCounter) save() {
+	b, err := json.Marshal(counter.attempts)
+	if err != nil {
+		Logger().WithError(err).Warn("Failed serializing message attempts")
+		return
+	}
+
+	// write to a temporary file first, and then rename it, so a crash never leaves a partially-written file
+	tempPath := counter.path + ".tmp"
+	if err := ioutil.WriteFile(tempPath, b, 0644); err != nil {
+		Logger().WithError(err).Warnf("Failed writing message attempts to '%s'", tempPath)
+	} else if err := os.Rename(tempPath, counter.path); err != nil {
+		Logger().WithError(err).Warnf("Failed writing message attempts to '%s'", counter.path)
+	}
+}
@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAttemptsSurviveRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitzup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := attemptsPath(dir, "agent-1")
+
+	counter, err := newAttemptCounter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.increment("m1")
+	counter.increment("m1")
+	counter.increment("m2")
+	counter.forget("m2")
+
+	counter, err = newAttemptCounter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt := counter.increment("m1"); attempt != 3 {
+		t.Errorf("expected third attempt at message 'm1' after restart, got %d", attempt)
+	}
+	if attempt := counter.increment("m2"); attempt != 1 {
+		t.Errorf("expected forgotten message 'm2' to start over, got attempt %d", attempt)
+	}
+	if other, err := newAttemptCounter(attemptsPath(dir, "agent-2")); err != nil {
+		t.Fatal(err)
+	} else if attempt := other.increment("m1"); attempt != 1 {
+		t.Errorf("expected other instances to count their own attempts, got attempt %d", attempt)
+	}
+}
+
+func TestAttemptsIgnoreCorruptFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitzup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".attempts.json")
+	if err := ioutil.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	counter, err := newAttemptCounter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt := counter.increment("m1"); attempt != 1 {
+		t.Errorf("expected first attempt, got %d", attempt)
+	}
+}
+
+func TestAttemptsPruning(t *testing.T) {
+	counter := &attemptCounter{attempts: make(map[string]*messageAttempts)}
+	now := time.Now()
+	counter.attempts["expired"] = &messageAttempts{Count: 1, LastSeen: now.Add(-attemptsExpiry - time.Minute)}
+	counter.attempts["recent"] = &messageAttempts{Count: 1, LastSeen: now}
+
+	counter.prune(now)
+	if _, ok := counter.attempts["expired"]; ok {
+		t.Error("expected expired message to be forgotten")
+	}
+	if _, ok := counter.attempts["recent"]; !ok {
+		t.Error("expected recent message to be kept")
+	}
+}
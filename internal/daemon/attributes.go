@@ -0,0 +1,140 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/go-errors/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Message attributes controlling how a build request is processed. All attributes are optional.
+const (
+	// Caller-assigned build ID, used as the build request ID; defaults to the message ID.
+	AttributeBuildID = "buildId"
+
+	// Maximum duration of the whole build request (eg. "30m"); defaults to the agent's build timeout.
+	AttributeTimeout = "timeout"
+
+	// Project the build request belongs to; defaults to the agent's project.
+	AttributeProject = "project"
+
+	// Whether to apply the build request ("apply", the default) or only plan it ("plan").
+	AttributeMode = "mode"
+
+	// Minimum log level for the build request's logs (eg. "debug"); defaults to the agent's log level.
+	AttributeLogLevel = "logLevel"
+
+	// Caller-assigned ID correlating the build request with the caller's records; included in all logs of the build
+	// request and in its notifications.
+	AttributeCorrelationID = "correlationId"
+)
+
+// Pattern of build IDs & project names, which must be usable in container, network & directory names.
+var identifierPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,127}$`)
+
+// Maximum length of correlation IDs.
+const maxCorrelationIDLength = 256
+
+// Validated attributes of a build request message.
+type messageAttributes struct {
+	BuildID       string
+	Timeout       time.Duration
+	Project       string
+	Mode          build.Mode
+	LogLevel      *logrus.Level
+	CorrelationID string
+}
+
+// Parses & validates the attributes of the given message. Even on failure, the returned attributes have a build ID &
+// mode, and any valid correlation ID, so the failure can be reported.
+func parseAttributes(msg *pubsub.Message) (*messageAttributes, error) {
+	attributes := &messageAttributes{BuildID: msg.ID, Mode: build.ModeApply}
+
+	if correlationID, ok := msg.Attributes[AttributeCorrelationID]; ok {
+		if correlationID == "" || len(correlationID) > maxCorrelationIDLength {
+			return attributes, errors.Errorf("illegal %s attribute (must be 1-%d characters)", AttributeCorrelationID, maxCorrelationIDLength)
+		}
+		attributes.CorrelationID = correlationID
+	}
+
+	if buildID, ok := msg.Attributes[AttributeBuildID]; ok {
+		if !identifierPattern.MatchString(buildID) {
+			return attributes, errors.Errorf("illegal %s attribute '%s' (must match '%s')", AttributeBuildID, buildID, identifierPattern)
+		}
+		attributes.BuildID = buildID
+	}
+
+	if mode, ok := msg.Attributes[AttributeMode]; ok {
+		switch build.Mode(mode) {
+		case build.ModeApply, build.ModePlan:
+			attributes.Mode = build.Mode(mode)
+		default:
+			return attributes, errors.Errorf("illegal %s attribute '%s' (must be '%s' or '%s')", AttributeMode, mode, build.ModeApply, build.ModePlan)
+		}
+	}
+
+	if timeout, ok := msg.Attributes[AttributeTimeout]; ok {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return attributes, errors.WrapPrefix(err, fmt.Sprintf("illegal %s attribute '%s'", AttributeTimeout, timeout), 0)
+		} else if duration <= 0 {
+			return attributes, errors.Errorf("illegal %s attribute '%s' (must be positive)", AttributeTimeout, timeout)
+		}
+		attributes.Timeout = duration
+	}
+
+	if project, ok := msg.Attributes[AttributeProject]; ok {
+		if !identifierPattern.MatchString(project) {
+			return attributes, errors.Errorf("illegal %s attribute '%s' (must match '%s')", AttributeProject, project, identifierPattern)
+		}
+		attributes.Project = project
+	}
+
+	if logLevel, ok := msg.Attributes[AttributeLogLevel]; ok {
+		level, err := logrus.ParseLevel(logLevel)
+		if err != nil {
+			return attributes, errors.WrapPrefix(err, fmt.Sprintf("illegal %s attribute '%s'", AttributeLogLevel, logLevel), 0)
+		}
+		attributes.LogLevel = &level
+	}
+
+	return attributes, nil
+}
+
+// Returns a child of the given context for processing the build request, carrying its ID, correlation ID & logger.
+func (attributes *messageAttributes) context(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, "request", attributes.BuildID)
+	if attributes.CorrelationID != "" {
+		ctx = context.WithValue(ctx, "correlation", attributes.CorrelationID)
+	}
+	if attributes.LogLevel != nil {
+		ctx = context.WithValue(ctx, "logger", LoggerWithLevel(*attributes.LogLevel))
+	}
+	return ctx
+}
+
+// Returns the given build request options, overridden by these attributes.
+func (attributes *messageAttributes) apply(options build.Options) build.Options {
+	if attributes.Timeout > 0 {
+		options.Timeout = attributes.Timeout
+	}
+	if attributes.Project != "" {
+		options.Project = attributes.Project
+	}
+	return options
+}
+
+// Returns the attributes identifying the build request in its notifications.
+func (attributes *messageAttributes) notificationAttributes() map[string]string {
+	notificationAttributes := map[string]string{AttributeRequestID: attributes.BuildID}
+	if attributes.CorrelationID != "" {
+		notificationAttributes[AttributeCorrelationID] = attributes.CorrelationID
+	}
+	return notificationAttributes
+}
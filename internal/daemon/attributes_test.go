@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseAttributesDefaults(t *testing.T) {
+	attributes, err := parseAttributes(&pubsub.Message{ID: "msg-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if attributes.BuildID != "msg-1" || attributes.Mode != build.ModeApply || attributes.Timeout != 0 ||
+		attributes.Project != "" || attributes.LogLevel != nil || attributes.CorrelationID != "" {
+		t.Errorf("unexpected default attributes: %+v", attributes)
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	attributes, err := parseAttributes(&pubsub.Message{ID: "msg-1", Attributes: map[string]string{
+		AttributeBuildID:       "build_1.2-3",
+		AttributeTimeout:       "90s",
+		AttributeProject:       "my-project",
+		AttributeMode:          "plan",
+		AttributeLogLevel:      "debug",
+		AttributeCorrelationID: "caller/ticket#42",
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if attributes.BuildID != "build_1.2-3" || attributes.Timeout != 90*time.Second || attributes.Project != "my-project" ||
+		attributes.Mode != build.ModePlan || attributes.CorrelationID != "caller/ticket#42" {
+		t.Errorf("unexpected attributes: %+v", attributes)
+	} else if attributes.LogLevel == nil || *attributes.LogLevel != logrus.DebugLevel {
+		t.Errorf("expected debug log level, got %v", attributes.LogLevel)
+	}
+
+	options := attributes.apply(build.Options{Timeout: time.Hour, Project: "default"})
+	if options.Timeout != 90*time.Second || options.Project != "my-project" {
+		t.Errorf("expected attributes to override options, got timeout %s and project '%s'", options.Timeout, options.Project)
+	}
+	notificationAttributes := attributes.notificationAttributes()
+	if notificationAttributes[AttributeRequestID] != "build_1.2-3" || notificationAttributes[AttributeCorrelationID] != "caller/ticket#42" {
+		t.Errorf("unexpected notification attributes: %v", notificationAttributes)
+	}
+}
+
+func TestParseAttributesRejectsIllegalValues(t *testing.T) {
+	illegal := []map[string]string{
+		{AttributeBuildID: ""},
+		{AttributeBuildID: "-leading-dash"},
+		{AttributeBuildID: "../escape"},
+		{AttributeBuildID: "with space"},
+		{AttributeBuildID: strings.Repeat("a", 129)},
+		{AttributeTimeout: "soon"},
+		{AttributeTimeout: "0s"},
+		{AttributeTimeout: "-5m"},
+		{AttributeProject: "my/project"},
+		{AttributeMode: "destroy"},
+		{AttributeLogLevel: "verbose"},
+		{AttributeCorrelationID: ""},
+		{AttributeCorrelationID: strings.Repeat("c", maxCorrelationIDLength+1)},
+	}
+	for _, attrs := range illegal {
+		if _, err := parseAttributes(&pubsub.Message{ID: "msg-1", Attributes: attrs}); err == nil {
+			t.Errorf("expected attributes %v to be rejected", attrs)
+		}
+	}
+	if _, err := parseAttributes(&pubsub.Message{ID: "msg-1", Attributes: map[string]string{
+		AttributeBuildID: strings.Repeat("a", 128),
+	}}); err != nil {
+		t.Errorf("expected build ID of 128 characters to be accepted: %s", err)
+	}
+}
+
+func TestParseAttributesKeepsReportableAttributesOnFailure(t *testing.T) {
+	attributes, err := parseAttributes(&pubsub.Message{ID: "msg-1", Attributes: map[string]string{
+		AttributeCorrelationID: "corr-1",
+		AttributeBuildID:       "build-1",
+		AttributeMode:          "plan",
+		AttributeTimeout:       "never",
+	}})
+	if err == nil {
+		t.Fatal("expected illegal timeout to be rejected")
+	}
+	if attributes.BuildID != "build-1" || attributes.Mode != build.ModePlan || attributes.CorrelationID != "corr-1" {
+		t.Errorf("expected valid attributes to be kept for reporting the failure, got %+v", attributes)
+	}
+}
@@ -0,0 +1,551 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/gitzup/agent/pkg/assets"
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/go-errors/errors"
+)
+
+// Configuration of the daemon.
+type Config struct {
+	// Subscription to receive build requests from (required).
+	Subscription *pubsub.Subscription
+
+	// Topic to publish messages that could not be processed to, along with the failure; if nil, such messages are only
+	// logged.
+	ErrorsTopic *pubsub.Topic
+
+	// Topic to publish build notifications to: a "started" notification when a build request starts, and its result
+	// (see the "build.response.json" schema) once it reaches a terminal outcome; if nil, no notifications are published.
+	ResultsTopic *pubsub.Topic
+
+	// Maximum number of attempts at processing a message before giving up on it; values lower than 1 are treated as 1.
+	// Attempts are counted by this agent instance, and persisted under the workspace directory so they survive restarts;
+	// attempts made by other agents that a message was redelivered to are not accounted for.
+	MaxAttempts int
+
+	// Delay before a message that failed transiently is returned for redelivery. The delay doubles on each attempt, up
+	// to the maximum backoff; if zero, messages are returned right away. Messages are held while backing off (without
+	// a build slot), and count towards the maximum number of outstanding messages: at most MaxOutstandingMessages -
+	// MaxConcurrentBuilds messages back off at once, so that backing off never stalls other messages; beyond that,
+	// messages are returned right away.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// Maximum number of build requests to process concurrently; values lower than 1 are treated as 1. Messages received
+	// while all build slots are taken wait for one to free up (with their acknowledgement deadline extended).
+	MaxConcurrentBuilds int
+
+	// Maximum number of messages received but not yet acknowledged (ie. being processed, waiting for a build slot, or
+	// backing off before being returned for redelivery); if zero, defaults to twice the maximum number of concurrent
+	// builds, leaving room for as many messages backing off.
+	MaxOutstandingMessages int
+
+	// Maximum duration to wait for build requests in flight to finish once the daemon stops receiving messages; build
+	// requests still in flight afterwards are cancelled, and their messages returned for redelivery. If zero, they are
+	// cancelled right away.
+	DrainTimeout time.Duration
+
+	// Maximum duration for which the acknowledgement deadline of a message is extended while it's being processed;
+	// should exceed the longest expected build. If zero, the Pub/Sub client's default is used.
+	MaxExtension time.Duration
+
+	// Directory to place build request workspaces in.
+	WorkspacePath string
+
+	// Options for processing build requests; message attributes may override some of them (see AttributeTimeout and
+	// AttributeProject).
+	Options build.Options
+}
+
+// Receives build requests from a Pub/Sub subscription and processes them. Messages are only acknowledged once their
+// build request reached a terminal outcome; messages that failed transiently are returned for redelivery (with
+// backoff), until they exhaust their attempts and are published to the errors topic instead.
+type Daemon struct {
+	config Config
+
+	// build slots, one per build request being processed
+	builds chan struct{}
+
+	// backoff slots, one per message held while backing off before being returned for redelivery
+	backoffs chan struct{}
+
+	// closed once the daemon stops receiving messages; no build requests are started afterwards
+	stopping chan struct{}
+
+	// attempts made at processing each message by this agent instance
+	attempts *attemptCounter
+}
+
+// Failed message, as published to the errors topic.
+type DeadLetter struct {
+	MessageID  string            `json:"messageId"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Data       []byte            `json:"data"`
+	Attempts   int               `json:"attempts"`
+	Error      string            `json:"error"`
+	StackTrace string            `json:"stackTrace,omitempty"`
+	FailedAt   time.Time         `json:"failedAt"`
+
+	// Whether changes were (or may have been) applied to any resource before the build request was given up on.
+	PartiallyApplied bool `json:"partiallyApplied,omitempty"`
+}
+
+// Attributes of messages published to the results topic.
+const (
+	// Type of notification: "started" or "finished".
+	AttributeEvent = "event"
+
+	// ID of the build request. Notifications also carry the build request's correlation ID (see
+	// AttributeCorrelationID), if it has one.
+	AttributeRequestID = "requestId"
+
+	// Status of the build request (only for "finished" notifications).
+	AttributeStatus = "status"
+
+	// Duration of the build request in milliseconds (only for "finished" notifications).
+	AttributeDurationMs = "durationMs"
+)
+
+// Types of notifications published to the results topic.
+const (
+	EventStarted  = "started"
+	EventFinished = "finished"
+)
+
+// Notification published to the results topic when a build request starts.
+type StartedNotification struct {
+	RequestId string    `json:"requestId"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// An error which retrying will not resolve (eg. an illegal build request).
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Creates a new daemon with the given configuration.
+func New(config Config) (*Daemon, error) {
+	if config.Subscription == nil {
+		return nil, errors.New("subscription is required")
+	} else if config.Options.Runtime == nil {
+		return nil, errors.New("container runtime is required")
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	if config.MaxConcurrentBuilds < 1 {
+		config.MaxConcurrentBuilds = 1
+	}
+	if config.MaxOutstandingMessages < 1 {
+		config.MaxOutstandingMessages = 2 * config.MaxConcurrentBuilds
+	}
+	maxBackingOff := config.MaxOutstandingMessages - config.MaxConcurrentBuilds
+	if maxBackingOff < 0 {
+		maxBackingOff = 0
+	}
+	config.Subscription.ReceiveSettings.MaxOutstandingMessages = config.MaxOutstandingMessages
+	if config.MaxExtension > 0 {
+		config.Subscription.ReceiveSettings.MaxExtension = config.MaxExtension
+	}
+	attempts, err := newAttemptCounter(attemptsPath(config.WorkspacePath, config.Options.Instance))
+	if err != nil {
+		return nil, err
+	}
+	if limiter := config.Options.TypeLimiter; limiter != nil {
+		metrics.Set("resourcesInFlight", expvar.Func(func() interface{} { return limiter.InFlight() }))
+	}
+	return &Daemon{
+		config:   config,
+		builds:   make(chan struct{}, config.MaxConcurrentBuilds),
+		backoffs: make(chan struct{}, maxBackingOff),
+		stopping: make(chan struct{}),
+		attempts: attempts,
+	}, nil
+}
+
+// Receives & processes messages until the given context is done, or an unrecoverable error occurs. Once the context is
+// done, no more messages are received, and build requests in flight are drained (see Config.DrainTimeout). Returns once
+// all received messages were acknowledged or returned for redelivery.
+func (d *Daemon) Run(ctx context.Context) error {
+
+	// build requests are processed with a separate context, since the Pub/Sub client cancels the context of received
+	// messages as soon as it stops receiving
+	buildsCtx, cancelBuilds := context.WithCancel(context.Background())
+	defer cancelBuilds()
+	received := make(chan struct{})
+	go d.drain(ctx, received, cancelBuilds)
+
+	Logger().Infof("Subscribing to: %s (up to %d concurrent builds, %d outstanding messages)", d.config.Subscription, d.config.MaxConcurrentBuilds, d.config.MaxOutstandingMessages)
+	err := d.config.Subscription.Receive(ctx, func(receiveCtx context.Context, msg *pubsub.Message) {
+		d.handleMessage(buildsCtx, receiveCtx, msg)
+	})
+	close(received)
+	if err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed receiving messages from '%s'", d.config.Subscription), 0)
+	}
+	Logger().Info("Stopped receiving messages; all messages were handled")
+	return nil
+}
+
+// Waits until the given context is done (unless receiving stops first), then stops starting build requests, and
+// cancels build requests still in flight once the drain timeout expires (unless all messages are handled first).
+func (d *Daemon) drain(ctx context.Context, received <-chan struct{}, cancelBuilds context.CancelFunc) {
+	select {
+	case <-ctx.Done():
+	case <-received:
+		return
+	}
+	close(d.stopping)
+	Logger().Infof("Stopping; draining %d build(s) in flight (for up to %s)", len(d.builds), d.config.DrainTimeout)
+
+	timer := time.NewTimer(d.config.DrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		Logger().Warnf("Drain timeout expired; cancelling %d build(s) in flight", len(d.builds))
+		cancelBuilds()
+	case <-received:
+	}
+}
+
+// Returns whether the daemon stopped receiving messages.
+func (d *Daemon) isStopping() bool {
+	select {
+	case <-d.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// Processes the given message, and acknowledges it (on terminal outcomes) or returns it for redelivery (on transient
+// failures, or when interrupted by the daemon stopping). The receive context is done once the Pub/Sub client stops
+// receiving messages.
+func (d *Daemon) handleMessage(ctx context.Context, receiveCtx context.Context, msg *pubsub.Message) {
+	messagesOutstanding.Add(1)
+	defer messagesOutstanding.Add(-1)
+	if d.isStopping() {
+		messagesNacked.Add(1)
+		msg.Nack()
+		return
+	}
+	attempt := d.attempts.increment(msg.ID)
+	startedAt := time.Now()
+
+	// invalid attributes are rejected, but still reported under the (default) build ID
+	attributes, err := parseAttributes(msg)
+	ctx = attributes.context(ctx)
+	var result *build.Result
+	if err != nil {
+		err = &permanentError{err}
+	} else {
+		result, err = d.process(ctx, msg, attributes)
+	}
+	if err == nil {
+		d.attempts.forget(msg.ID)
+		d.publishResult(ctx, attributes, result)
+		messagesAcked.Add(1)
+		msg.Ack()
+		return
+	}
+
+	// build requests that are given up on are reported as failed, keeping the outcome of each processed resource
+	if result == nil {
+		result = failedResult(attributes.BuildID, attributes.Mode, startedAt, err)
+	} else if result.Status != build.StatusFailed {
+		result.Status = build.StatusFailed
+		result.Error = &build.ErrorDetails{Message: err.Error()}
+	}
+	if partiallyApplied(result) {
+		From(ctx).Warnf("Build request of message '%s' was partially applied", msg.ID)
+	}
+	if _, permanent := err.(*permanentError); !permanent && d.isStopping() {
+		// leave it to another agent (or to this agent once restarted)
+		From(ctx).WithError(err).Warnf("Returning message '%s' for redelivery, since the daemon is stopping", msg.ID)
+		messagesNacked.Add(1)
+		msg.Nack()
+	} else if permanent {
+		From(ctx).WithError(err).Errorf("Rejecting message '%s'", msg.ID)
+		d.publishResult(ctx, attributes, result)
+		d.reject(ctx, msg, attempt, result, err)
+	} else if attempt >= d.config.MaxAttempts {
+		From(ctx).WithError(err).Errorf("Giving up on message '%s' after %d attempts", msg.ID, attempt)
+		d.publishResult(ctx, attributes, result)
+		d.reject(ctx, msg, attempt, result, err)
+	} else if backoff := d.backoff(attempt); backoff > 0 && d.acquireBackoffSlot() {
+		From(ctx).WithError(err).Warnf("Failed processing message '%s' (attempt %d of %d); returning it for redelivery in %s", msg.ID, attempt, d.config.MaxAttempts, backoff)
+		d.backOff(receiveCtx, backoff)
+		messagesNacked.Add(1)
+		msg.Nack()
+	} else {
+		From(ctx).WithError(err).Warnf("Failed processing message '%s' (attempt %d of %d); returning it for redelivery", msg.ID, attempt, d.config.MaxAttempts)
+		messagesNacked.Add(1)
+		msg.Nack()
+	}
+}
+
+// Processes the given message's build request according to the given (validated) message attributes, returning its
+// result (if it was processed). Returns a nil error if the build request reached a terminal outcome (even if it failed),
+// a permanent error if the message can never be processed, or any other error for transient failures.
+func (d *Daemon) process(ctx context.Context, msg *pubsub.Message, attributes *messageAttributes) (result *build.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Wrap(r, 2)
+		}
+	}()
+
+	options := attributes.apply(d.config.Options)
+	request, err := build.New(attributes.BuildID, d.config.WorkspacePath, msg.Data, options)
+	if err != nil {
+		return nil, &permanentError{errors.WrapPrefix(err, "illegal build request", 0)}
+	}
+
+	release, err := d.acquireBuildSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	d.publishStarted(ctx, attributes)
+	if attributes.Mode == build.ModePlan {
+		result, err = request.Plan(ctx)
+	} else {
+		result, err = request.Apply(ctx)
+	}
+	if err != nil && isRetryable(result) {
+		return result, err
+	}
+	return result, nil
+}
+
+// Returns the result reported for a build request that could not be processed.
+func failedResult(requestID string, mode build.Mode, startedAt time.Time, err error) *build.Result {
+	finishedAt := time.Now()
+	return &build.Result{
+		RequestId:  requestID,
+		Mode:       mode,
+		Status:     build.StatusFailed,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+		DurationMs: finishedAt.Sub(startedAt).Nanoseconds() / int64(time.Millisecond),
+		Resources:  make(map[string]*build.ResourceResult),
+		Error:      &build.ErrorDetails{Message: err.Error()},
+	}
+}
+
+// Publishes a "started" notification for the given build request to the results topic (if any).
+func (d *Daemon) publishStarted(ctx context.Context, attributes *messageAttributes) {
+	if d.config.ResultsTopic == nil {
+		return
+	}
+	b, err := json.Marshal(StartedNotification{RequestId: attributes.BuildID, StartedAt: time.Now()})
+	if err != nil {
+		From(ctx).WithError(err).Error("Failed serializing build started notification")
+		return
+	}
+	notificationAttributes := attributes.notificationAttributes()
+	notificationAttributes[AttributeEvent] = EventStarted
+	if err := publish(d.config.ResultsTopic, b, notificationAttributes); err != nil {
+		From(ctx).WithError(err).Error("Failed publishing build started notification")
+	}
+}
+
+// Publishes the given build result to the results topic (if any), as a "finished" notification. Failures are only
+// logged, since the build request has already been processed.
+func (d *Daemon) publishResult(ctx context.Context, attributes *messageAttributes, result *build.Result) {
+	if d.config.ResultsTopic == nil {
+		return
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		From(ctx).WithError(err).Error("Failed serializing build result")
+		return
+	} else if err := assets.GetBuildResponseSchema().Validate(b); err != nil {
+		From(ctx).WithError(err).Error("Not publishing illegal build result")
+		return
+	}
+	notificationAttributes := attributes.notificationAttributes()
+	notificationAttributes[AttributeEvent] = EventFinished
+	notificationAttributes[AttributeStatus] = string(result.Status)
+	notificationAttributes[AttributeDurationMs] = fmt.Sprintf("%d", result.DurationMs)
+	if err := publish(d.config.ResultsTopic, b, notificationAttributes); err != nil {
+		From(ctx).WithError(err).Error("Failed publishing build result")
+	}
+}
+
+// Publishes a message with the given data & attributes to the given topic, and waits for it to be published.
+func publish(topic *pubsub.Topic, data []byte, attributes map[string]string) error {
+	// use a separate context, since the caller's context may be done (eg. when shutting down)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := topic.Publish(ctx, &pubsub.Message{Data: data, Attributes: attributes}).Get(ctx); err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed publishing to '%s'", topic), 0)
+	}
+	return nil
+}
+
+// Returns whether a failed build request with the given result may be retried: either because some of its resources
+// were interrupted (eg. the daemon is shutting down) or never processed (eg. because the container runtime was
+// unavailable), while none of its resources failed. Resources failing is a terminal outcome, even if other resources
+// were interrupted meanwhile.
+func isRetryable(result *build.Result) bool {
+	if result == nil {
+		return true
+	}
+	incomplete := false
+	for _, resource := range result.Resources {
+		switch resource.Status {
+		case build.ResourceStatusFailed:
+			return false
+		case build.ResourceStatusCancelled, build.ResourceStatusSkipped:
+			incomplete = true
+		}
+	}
+	return incomplete || len(result.Resources) == 0
+}
+
+// Returns whether changes were (or may have been) applied to any resource of the given unsuccessful build result.
+func partiallyApplied(result *build.Result) bool {
+	if result == nil || result.Mode != build.ModeApply {
+		return false
+	}
+	for _, resource := range result.Resources {
+		if resource.Status == build.ResourceStatusApplied {
+			return true
+		} else if resource.Error != nil && resource.Error.Phase == build.PhaseApply {
+			return true
+		}
+	}
+	return false
+}
+
+// Publishes the given message to the errors topic along with the given error (and whether its given result was
+// partially applied), and acknowledges it. If publishing fails, the message is returned for redelivery instead, so it's
+// never lost.
+func (d *Daemon) reject(ctx context.Context, msg *pubsub.Message, attempts int, result *build.Result, err error) {
+	d.attempts.forget(msg.ID)
+	if d.config.ErrorsTopic == nil {
+		messagesRejected.Add(1)
+		msg.Ack()
+		return
+	}
+
+	deadLetter := DeadLetter{
+		MessageID:  msg.ID,
+		Attributes: msg.Attributes,
+		Data:       msg.Data,
+		Attempts:   attempts,
+		Error:      err.Error(),
+		FailedAt:   time.Now(),
+
+		PartiallyApplied: partiallyApplied(result),
+	}
+	if permanent, ok := err.(*permanentError); ok {
+		err = permanent.err
+	}
+	if stackErr, ok := err.(*errors.Error); ok {
+		deadLetter.StackTrace = stackErr.ErrorStack()
+	}
+	b, marshalErr := json.Marshal(deadLetter)
+	if marshalErr != nil {
+		From(ctx).WithError(marshalErr).Errorf("Failed serializing message '%s' for errors topic", msg.ID)
+		messagesNacked.Add(1)
+		msg.Nack()
+		return
+	}
+
+	attributes := map[string]string{"messageId": msg.ID}
+	for key, value := range msg.Attributes {
+		attributes[key] = value
+	}
+	if err := publish(d.config.ErrorsTopic, b, attributes); err != nil {
+		From(ctx).WithError(err).Errorf("Failed publishing message '%s' to errors topic", msg.ID)
+		messagesNacked.Add(1)
+		msg.Nack()
+		return
+	}
+	messagesRejected.Add(1)
+	msg.Ack()
+}
+
+// Waits for a free build slot, returning a function to call once the build request is done. Returns an error if the
+// daemon stops first.
+func (d *Daemon) acquireBuildSlot(ctx context.Context) (func(), error) {
+	select {
+	case d.builds <- struct{}{}:
+	default:
+		From(ctx).Infof("Waiting for one of %d build(s) in flight to finish", cap(d.builds))
+		buildsWaiting.Add(1)
+		select {
+		case d.builds <- struct{}{}:
+			buildsWaiting.Add(-1)
+		case <-d.stopping:
+			buildsWaiting.Add(-1)
+			return nil, errors.New("daemon stopped before a build slot freed up")
+		}
+	}
+	if d.isStopping() {
+		<-d.builds
+		return nil, errors.New("daemon stopped before the build request started")
+	}
+	buildsInFlight.Add(1)
+	From(ctx).WithField("buildsInFlight", len(d.builds)).Info("Build started")
+	return func() {
+		buildsInFlight.Add(-1)
+		<-d.builds
+		From(ctx).WithField("buildsInFlight", len(d.builds)).Info("Build finished")
+	}, nil
+}
+
+// Returns the delay before redelivering a message after the given (failed) attempt.
+func (d *Daemon) backoff(attempt int) time.Duration {
+	backoff := d.config.MinBackoff
+	for i := 1; i < attempt && backoff < d.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if d.config.MaxBackoff > 0 && backoff > d.config.MaxBackoff {
+		backoff = d.config.MaxBackoff
+	}
+	return backoff
+}
+
+// Takes a free backoff slot, if any, returning whether one was taken (and must be released by backOff).
+func (d *Daemon) acquireBackoffSlot() bool {
+	select {
+	case d.backoffs <- struct{}{}:
+		messagesBackingOff.Add(1)
+		return true
+	default:
+		return false
+	}
+}
+
+// Waits for the given backoff to expire (unless the daemon or the Pub/Sub client stops receiving messages first), then
+// releases the backoff slot taken by acquireBackoffSlot. The message's build slot was already released, so other
+// messages are processed in the meantime.
+func (d *Daemon) backOff(receiveCtx context.Context, backoff time.Duration) {
+	defer func() {
+		messagesBackingOff.Add(-1)
+		<-d.backoffs
+	}()
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-d.stopping:
+	case <-receiveCtx.Done():
+	}
+}
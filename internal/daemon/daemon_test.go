@@ -0,0 +1,302 @@
+package daemon_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/gitzup/agent/internal/daemon"
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/go-errors/errors"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+)
+
+// Build request with a single resource, which the test runtime initializes & finds up-to-date.
+const buildRequest = `{"resources": {"app": {"type": "gitzup/test:dev", "config": {}}}}`
+
+// Fake runtime whose build networks cannot be created for a given number of times, simulating a transient failure
+// (eg. the Docker daemon being unavailable).
+type flakyRuntime struct {
+	*build.FakeRuntime
+	mutex    sync.Mutex
+	failures int
+}
+
+func (rt *flakyRuntime) CreateNetwork(ctx context.Context, name string, labels map[string]string) (string, error) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	if rt.failures > 0 {
+		rt.failures--
+		return "", errors.New("runtime unavailable")
+	}
+	return rt.FakeRuntime.CreateNetwork(ctx, name, labels)
+}
+
+// Runs a daemon against an in-memory Pub/Sub server, receiving build requests from the "requests" topic, and
+// publishing to the "errors" & "results" topics.
+type harness struct {
+	t         *testing.T
+	server    *pstest.Server
+	client    *pubsub.Client
+	requests  *pubsub.Topic
+	runtime   *flakyRuntime
+	workspace string
+	cancel    context.CancelFunc
+	done      chan error
+}
+
+// Starts a daemon giving up on messages after the given number of attempts, whose runtime fails the given number of
+// times before recovering. The daemon's configuration may be further adjusted by the given functions.
+func newHarness(t *testing.T, maxAttempts int, failures int, configure ...func(*daemon.Config)) *harness {
+	ctx := context.Background()
+	h := &harness{t: t, server: pstest.NewServer(), done: make(chan error, 1)}
+
+	conn, err := grpc.Dial(h.server.Addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.client, err = pubsub.NewClient(ctx, "test", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.requests, err = h.client.CreateTopic(ctx, "requests"); err != nil {
+		t.Fatal(err)
+	}
+	subscription, err := h.client.CreateSubscription(ctx, "agent", pubsub.SubscriptionConfig{Topic: h.requests, AckDeadline: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorsTopic, err := h.client.CreateTopic(ctx, "errors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultsTopic, err := h.client.CreateTopic(ctx, "results")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.runtime = &flakyRuntime{FakeRuntime: build.NewFakeRuntime(), failures: failures}
+	h.runtime.Respond("gitzup/test:dev", "init", map[string]interface{}{
+		"configSchema": map[string]interface{}{"type": "object"},
+		"stateAction":  map[string]interface{}{"image": "gitzup/test:dev"},
+	})
+	h.runtime.Respond("gitzup/test:dev", "state", map[string]interface{}{"state": map[string]interface{}{}})
+	if h.workspace, err = ioutil.TempDir("", "gitzup-test"); err != nil {
+		t.Fatal(err)
+	}
+	options := build.DefaultOptions()
+	options.Runtime = h.runtime
+
+	config := daemon.Config{
+		Subscription:  subscription,
+		ErrorsTopic:   errorsTopic,
+		ResultsTopic:  resultsTopic,
+		MaxAttempts:   maxAttempts,
+		WorkspacePath: h.workspace,
+		Options:       options,
+	}
+	for _, f := range configure {
+		f(&config)
+	}
+	d, err := daemon.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	h.cancel = cancel
+	go func() { h.done <- d.Run(runCtx) }()
+	return h
+}
+
+// Stops the daemon, and releases the harness' resources.
+func (h *harness) close() {
+	h.cancel()
+	select {
+	case err := <-h.done:
+		if err != nil {
+			h.t.Errorf("daemon failed: %s", err)
+		}
+	case <-time.After(10 * time.Second):
+		h.t.Error("daemon did not stop")
+	}
+	h.client.Close()
+	h.server.Close()
+	os.RemoveAll(h.workspace)
+}
+
+// Publishes a build request with the given attributes, returning its message ID.
+func (h *harness) publish(data string, attributes map[string]string) string {
+	ctx := context.Background()
+	id, err := h.requests.Publish(ctx, &pubsub.Message{Data: []byte(data), Attributes: attributes}).Get(ctx)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return id
+}
+
+// Waits until the message with the given ID was acknowledged.
+func (h *harness) waitForAck(id string) *pstest.Message {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if msg := h.server.Message(id); msg != nil && msg.Acks > 0 {
+			return msg
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h.t.Fatalf("message '%s' was not acknowledged", id)
+	return nil
+}
+
+// Waits until the message with the given ID was delivered at least once.
+func (h *harness) waitForDelivery(id string) {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if msg := h.server.Message(id); msg != nil && msg.Deliveries > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h.t.Fatalf("message '%s' was not delivered", id)
+}
+
+// Returns the messages published to the errors topic.
+func (h *harness) deadLetters() []daemon.DeadLetter {
+	var deadLetters []daemon.DeadLetter
+	for _, msg := range h.server.Messages() {
+		if _, ok := msg.Attributes["messageId"]; ok {
+			var deadLetter daemon.DeadLetter
+			if err := json.Unmarshal(msg.Data, &deadLetter); err != nil {
+				h.t.Fatal(err)
+			}
+			deadLetters = append(deadLetters, deadLetter)
+		}
+	}
+	return deadLetters
+}
+
+// Returns the status of the given build request, as published to the results topic (if it finished).
+func (h *harness) status(requestID string) string {
+	for _, msg := range h.server.Messages() {
+		if msg.Attributes[daemon.AttributeRequestID] == requestID && msg.Attributes[daemon.AttributeEvent] == daemon.EventFinished {
+			return msg.Attributes[daemon.AttributeStatus]
+		}
+	}
+	return ""
+}
+
+func TestAcksSucceededBuildRequests(t *testing.T) {
+	h := newHarness(t, 3, 0)
+	defer h.close()
+
+	id := h.publish(buildRequest, map[string]string{daemon.AttributeBuildID: "build-1"})
+	msg := h.waitForAck(id)
+	if msg.Deliveries != 1 {
+		t.Errorf("expected message to be delivered once, got %d deliveries", msg.Deliveries)
+	}
+	if status := h.status("build-1"); status != string(build.StatusSucceeded) {
+		t.Errorf("expected build request to succeed, got '%s'", status)
+	}
+	if deadLetters := h.deadLetters(); len(deadLetters) > 0 {
+		t.Errorf("expected no dead letters, got %+v", deadLetters)
+	}
+}
+
+func TestRedeliversTransientFailures(t *testing.T) {
+	h := newHarness(t, 3, 1)
+	defer h.close()
+
+	id := h.publish(buildRequest, map[string]string{daemon.AttributeBuildID: "build-1"})
+	msg := h.waitForAck(id)
+	if msg.Deliveries != 2 {
+		t.Errorf("expected message to be delivered twice, got %d deliveries", msg.Deliveries)
+	}
+	if status := h.status("build-1"); status != string(build.StatusSucceeded) {
+		t.Errorf("expected build request to succeed, got '%s'", status)
+	}
+	if deadLetters := h.deadLetters(); len(deadLetters) > 0 {
+		t.Errorf("expected no dead letters, got %+v", deadLetters)
+	}
+}
+
+func TestBacksOffBeforeRedelivery(t *testing.T) {
+	backoff := time.Second
+	h := newHarness(t, 3, 1, func(config *daemon.Config) {
+		config.MinBackoff = backoff
+		config.MaxBackoff = backoff
+	})
+	defer h.close()
+
+	// the first message fails, and backs off; the second is processed in the meantime
+	publishedAt := time.Now()
+	failing := h.publish(buildRequest, map[string]string{daemon.AttributeBuildID: "build-1"})
+	h.waitForDelivery(failing)
+	other := h.publish(buildRequest, map[string]string{daemon.AttributeBuildID: "build-2"})
+	h.waitForAck(other)
+	if msg := h.server.Message(failing); msg.Acks > 0 || msg.Deliveries > 1 {
+		t.Errorf("expected message to back off while another one is processed, got %d deliveries", msg.Deliveries)
+	}
+
+	msg := h.waitForAck(failing)
+	if elapsed := time.Since(publishedAt); elapsed < backoff {
+		t.Errorf("expected message to be redelivered after %s, got acknowledged after %s", backoff, elapsed)
+	}
+	if msg.Deliveries != 2 {
+		t.Errorf("expected message to be delivered twice, got %d deliveries", msg.Deliveries)
+	}
+	if status := h.status("build-1"); status != string(build.StatusSucceeded) {
+		t.Errorf("expected build request to succeed, got '%s'", status)
+	}
+}
+
+func TestRejectsAfterMaxAttempts(t *testing.T) {
+	h := newHarness(t, 2, 100)
+	defer h.close()
+
+	id := h.publish(buildRequest, map[string]string{daemon.AttributeBuildID: "build-1"})
+	msg := h.waitForAck(id)
+	if msg.Deliveries != 2 {
+		t.Errorf("expected message to be delivered twice, got %d deliveries", msg.Deliveries)
+	}
+	if status := h.status("build-1"); status != string(build.StatusFailed) {
+		t.Errorf("expected build request to fail, got '%s'", status)
+	}
+	deadLetters := h.deadLetters()
+	if len(deadLetters) != 1 {
+		t.Fatalf("expected a single dead letter, got %+v", deadLetters)
+	} else if deadLetters[0].MessageID != id || deadLetters[0].Attempts != 2 {
+		t.Errorf("expected dead letter of message '%s' after 2 attempts, got %+v", id, deadLetters[0])
+	} else if !strings.Contains(deadLetters[0].Error, "runtime unavailable") {
+		t.Errorf("expected dead letter to carry the runtime failure, got: %s", deadLetters[0].Error)
+	}
+}
+
+func TestRejectsInvalidAttributes(t *testing.T) {
+	h := newHarness(t, 3, 0)
+	defer h.close()
+
+	id := h.publish(buildRequest, map[string]string{daemon.AttributeBuildID: "build-1", daemon.AttributeMode: "destroy"})
+	msg := h.waitForAck(id)
+	if msg.Deliveries != 1 {
+		t.Errorf("expected message to be delivered once, got %d deliveries", msg.Deliveries)
+	}
+	if status := h.status("build-1"); status != string(build.StatusFailed) {
+		t.Errorf("expected build request to fail, got '%s'", status)
+	}
+	deadLetters := h.deadLetters()
+	if len(deadLetters) != 1 {
+		t.Fatalf("expected a single dead letter, got %+v", deadLetters)
+	} else if !strings.Contains(deadLetters[0].Error, "illegal mode attribute") {
+		t.Errorf("expected dead letter to carry the attribute error, got: %s", deadLetters[0].Error)
+	}
+	if runs := h.runtime.Runs(); len(runs) > 0 {
+		t.Errorf("expected no actions to run, got %d", len(runs))
+	}
+}
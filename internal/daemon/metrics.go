@@ -0,0 +1,33 @@
+package daemon
+
+import "expvar"
+
+// Metrics of the daemon, published via expvar under "daemon" (served at "/debug/vars" if the agent serves metrics).
+var (
+	metrics = expvar.NewMap("daemon")
+
+	// Number of messages received but not yet acknowledged or returned for redelivery, and of those backing off before
+	// being returned for redelivery.
+	messagesOutstanding = new(expvar.Int)
+	messagesBackingOff  = new(expvar.Int)
+
+	// Number of build requests being processed, and waiting for a build slot to free up.
+	buildsInFlight = new(expvar.Int)
+	buildsWaiting  = new(expvar.Int)
+
+	// Number of messages acknowledged (after their build request reached a terminal outcome), returned for redelivery,
+	// and rejected (published to the errors topic, if any).
+	messagesAcked    = new(expvar.Int)
+	messagesNacked   = new(expvar.Int)
+	messagesRejected = new(expvar.Int)
+)
+
+func init() {
+	metrics.Set("messagesOutstanding", messagesOutstanding)
+	metrics.Set("messagesBackingOff", messagesBackingOff)
+	metrics.Set("buildsInFlight", buildsInFlight)
+	metrics.Set("buildsWaiting", buildsWaiting)
+	metrics.Set("messagesAcked", messagesAcked)
+	metrics.Set("messagesNacked", messagesNacked)
+	metrics.Set("messagesRejected", messagesRejected)
+}
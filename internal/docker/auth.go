@@ -0,0 +1,244 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/go-errors/errors"
+)
+
+// Registry of the Docker Hub, as referred to by image references without an explicit registry.
+const dockerHubRegistry = "docker.io"
+
+// Key of the Docker Hub registry in Docker's "config.json" file.
+const dockerHubConfigKey = "https://index.docker.io/v1/"
+
+// Credentials for authenticating against a Docker registry.
+type RegistryCredentials struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// Configuration of where registry credentials are resolved from. For each registry, credentials are looked up in the
+// following order, using the first source that provides them:
+//  * explicitly provided credentials
+//  * the credentials file
+//  * the Docker configuration directory (per-registry credential helpers, the default credentials store, and finally
+//    the credentials stored in "config.json" itself)
+type RegistryAuthConfig struct {
+	// Explicitly provided credentials, keyed by registry hostname.
+	Credentials map[string]RegistryCredentials
+
+	// Path of a JSON file mapping registry hostnames to credentials; if empty, no credentials file is used.
+	CredentialsFile string
+
+	// Directory containing Docker's "config.json" file; if empty, Docker configuration is not used.
+	DockerConfigDir string
+}
+
+// Docker's "config.json" file (only the parts relevant to registry authentication).
+type dockerConfigFile struct {
+	Auths       map[string]dockerConfigAuth `json:"auths"`
+	CredHelpers map[string]string           `json:"credHelpers"`
+	CredsStore  string                      `json:"credsStore"`
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// Response of a Docker credential helper's "get" command.
+type credentialHelperResponse struct {
+	Username string `json:"Username"`
+	Secret   string `json:"Secret"`
+}
+
+// Resolves registry credentials for images, and keeps track of all resolved secrets so they can be redacted from logs &
+// error messages.
+type registryAuthResolver struct {
+	config  RegistryAuthConfig
+	mutex   sync.Mutex
+	secrets []string
+}
+
+func newRegistryAuthResolver(config RegistryAuthConfig) *registryAuthResolver {
+	return &registryAuthResolver{config: config}
+}
+
+// Returns the registry hostname of the given image reference.
+func registryOf(image string) string {
+	slash := strings.Index(image, "/")
+	if slash < 0 {
+		return dockerHubRegistry
+	}
+	host := image[:slash]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return dockerHubRegistry
+	}
+	if host == "index.docker.io" || host == "registry-1.docker.io" {
+		return dockerHubRegistry
+	}
+	return host
+}
+
+// Returns the encoded registry authentication for pulling the given image, or an empty string if no credentials are
+// available for its registry.
+func (resolver *registryAuthResolver) RegistryAuth(image string) (string, error) {
+	registry := registryOf(image)
+	credentials, err := resolver.resolve(registry)
+	if err != nil {
+		return "", errors.WrapPrefix(err, fmt.Sprintf("failed resolving credentials for registry '%s'", registry), 0)
+	} else if credentials == nil {
+		return "", nil
+	}
+
+	b, err := json.Marshal(types.AuthConfig{
+		Username:      credentials.Username,
+		Password:      credentials.Password,
+		IdentityToken: credentials.IdentityToken,
+		ServerAddress: registry,
+	})
+	if err != nil {
+		return "", errors.WrapPrefix(err, "failed encoding registry credentials", 0)
+	}
+	encoded := base64.URLEncoding.EncodeToString(b)
+	resolver.addSecrets(credentials.Password, credentials.IdentityToken, encoded)
+	return encoded, nil
+}
+
+// Replaces all resolved secrets in the given text.
+func (resolver *registryAuthResolver) Redact(text string) string {
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+	for _, secret := range resolver.secrets {
+		text = strings.Replace(text, secret, "*****", -1)
+	}
+	return text
+}
+
+func (resolver *registryAuthResolver) addSecrets(secrets ...string) {
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+	for _, secret := range secrets {
+		if secret != "" {
+			resolver.secrets = append(resolver.secrets, secret)
+		}
+	}
+}
+
+func (resolver *registryAuthResolver) resolve(registry string) (*RegistryCredentials, error) {
+
+	// explicitly provided credentials
+	if credentials, ok := resolver.config.Credentials[registry]; ok {
+		return &credentials, nil
+	}
+
+	// credentials file
+	if resolver.config.CredentialsFile != "" {
+		b, err := ioutil.ReadFile(resolver.config.CredentialsFile)
+		if err != nil {
+			return nil, errors.WrapPrefix(err, fmt.Sprintf("failed reading credentials file '%s'", resolver.config.CredentialsFile), 0)
+		}
+		var file map[string]RegistryCredentials
+		if err := json.Unmarshal(b, &file); err != nil {
+			return nil, errors.WrapPrefix(err, fmt.Sprintf("failed parsing credentials file '%s'", resolver.config.CredentialsFile), 0)
+		}
+		if credentials, ok := file[registry]; ok {
+			return &credentials, nil
+		}
+	}
+
+	// Docker configuration
+	if resolver.config.DockerConfigDir != "" {
+		return resolveFromDockerConfig(path.Join(resolver.config.DockerConfigDir, "config.json"), registry)
+	}
+	return nil, nil
+}
+
+func resolveFromDockerConfig(configFile string, registry string) (*RegistryCredentials, error) {
+	b, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed reading Docker configuration '%s'", configFile), 0)
+	}
+	var config dockerConfigFile
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed parsing Docker configuration '%s'", configFile), 0)
+	}
+
+	// Docker Hub credentials are keyed by its index URL
+	key := registry
+	if registry == dockerHubRegistry {
+		key = dockerHubConfigKey
+	}
+
+	// credential helpers take precedence over credentials stored in the file
+	if helper, ok := config.CredHelpers[key]; ok {
+		return invokeCredentialHelper(helper, key)
+	} else if config.CredsStore != "" {
+		return invokeCredentialHelper(config.CredsStore, key)
+	}
+
+	for _, candidate := range []string{key, "https://" + key, "http://" + key} {
+		auth, ok := config.Auths[candidate]
+		if !ok {
+			continue
+		}
+		credentials := &RegistryCredentials{IdentityToken: auth.IdentityToken}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, errors.WrapPrefix(err, fmt.Sprintf("illegal credentials for registry '%s' in Docker configuration", registry), 0)
+			}
+			tokens := strings.SplitN(string(decoded), ":", 2)
+			if len(tokens) != 2 {
+				return nil, errors.Errorf("illegal credentials for registry '%s' in Docker configuration", registry)
+			}
+			credentials.Username, credentials.Password = tokens[0], tokens[1]
+		}
+		return credentials, nil
+	}
+	return nil, nil
+}
+
+// Invokes the given Docker credential helper ("docker-credential-<helper>") to get the credentials of the given
+// registry. Returns nil if the helper has no credentials for it.
+func invokeCredentialHelper(helper string, registry string) (*RegistryCredentials, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(message, "credentials not found") {
+			return nil, nil
+		} else if message != "" {
+			return nil, errors.WrapPrefix(err, fmt.Sprintf("credential helper '%s' failed: %s", helper, message), 0)
+		}
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("credential helper '%s' failed", helper), 0)
+	}
+
+	var response credentialHelperResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed parsing response of credential helper '%s'", helper), 0)
+	}
+
+	// helpers return identity tokens with a special username
+	if response.Username == "<token>" {
+		return &RegistryCredentials{IdentityToken: response.Secret}, nil
+	}
+	return &RegistryCredentials{Username: response.Username, Password: response.Secret}, nil
+}
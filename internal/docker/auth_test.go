@@ -0,0 +1,198 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestRegistryOf(t *testing.T) {
+	tests := map[string]string{
+		"alpine":                           dockerHubRegistry,
+		"gitzup/test:dev":                  dockerHubRegistry,
+		"docker.io/gitzup/test":            "docker.io",
+		"index.docker.io/gitzup/test":      dockerHubRegistry,
+		"registry-1.docker.io/gitzup/test": dockerHubRegistry,
+		"gcr.io/project/image:1.0":         "gcr.io",
+		"registry:5000/image":              "registry:5000",
+		"localhost/image":                  "localhost",
+		"localhost:5000/team/image":        "localhost:5000",
+	}
+	for image, expected := range tests {
+		if registry := registryOf(image); registry != expected {
+			t.Errorf("expected registry '%s' for image '%s', got '%s'", expected, image, registry)
+		}
+	}
+}
+
+// Decodes the given encoded registry authentication, as sent to the Docker daemon.
+func decodeRegistryAuth(t *testing.T, encoded string) types.AuthConfig {
+	var auth types.AuthConfig
+	b, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := json.Unmarshal(b, &auth); err != nil {
+		t.Fatal(err)
+	}
+	return auth
+}
+
+func TestRegistryAuthRedactsSecrets(t *testing.T) {
+	resolver := newRegistryAuthResolver(RegistryAuthConfig{
+		Credentials: map[string]RegistryCredentials{
+			"gcr.io":      {Username: "_json_key", Password: "s3cr3t-password"},
+			"quay.io":     {IdentityToken: "s3cr3t-token"},
+			"example.com": {Username: "anonymous"},
+		},
+	})
+
+	encoded, err := resolver.RegistryAuth("gcr.io/project/image")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	auth := decodeRegistryAuth(t, encoded)
+	if auth.Username != "_json_key" || auth.Password != "s3cr3t-password" || auth.ServerAddress != "gcr.io" {
+		t.Errorf("unexpected registry authentication: %+v", auth)
+	}
+	if _, err := resolver.RegistryAuth("quay.io/team/image"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := resolver.RegistryAuth("example.com/image"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	text := "pull failed (password s3cr3t-password, token s3cr3t-token, auth " + encoded + ") for anonymous"
+	redacted := resolver.Redact(text)
+	for _, secret := range []string{"s3cr3t-password", "s3cr3t-token", encoded} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("expected secret '%s' to be redacted from: %s", secret, redacted)
+		}
+	}
+	if expected := "pull failed (password *****, token *****, auth *****) for anonymous"; redacted != expected {
+		t.Errorf("expected '%s', got '%s'", expected, redacted)
+	}
+}
+
+func TestRegistryAuthWithoutCredentials(t *testing.T) {
+	resolver := newRegistryAuthResolver(RegistryAuthConfig{})
+	if encoded, err := resolver.RegistryAuth("gitzup/test"); err != nil || encoded != "" {
+		t.Errorf("expected no registry authentication, got '%s' (%v)", encoded, err)
+	}
+	if text := resolver.Redact("nothing to hide"); text != "nothing to hide" {
+		t.Errorf("expected text to be unchanged, got '%s'", text)
+	}
+}
+
+func TestRegistryAuthFromFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitzup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	credentialsFile := filepath.Join(dir, "credentials.json")
+	credentials := `{"registry:5000": {"username": "file-user", "password": "file-password"}}`
+	if err := ioutil.WriteFile(credentialsFile, []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	dockerConfig := `{"auths": {
+		"https://index.docker.io/v1/": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("hub-user:hub:password")) + `"},
+		"gcr.io": {"identitytoken": "gcr-token"}
+	}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(dockerConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	resolver := newRegistryAuthResolver(RegistryAuthConfig{CredentialsFile: credentialsFile, DockerConfigDir: dir})
+
+	expected := map[string]types.AuthConfig{
+		"registry:5000/image":   {Username: "file-user", Password: "file-password", ServerAddress: "registry:5000"},
+		"gitzup/test":           {Username: "hub-user", Password: "hub:password", ServerAddress: dockerHubRegistry},
+		"gcr.io/project/image":  {IdentityToken: "gcr-token", ServerAddress: "gcr.io"},
+		"quay.io/team/image:99": {},
+	}
+	for image, expectedAuth := range expected {
+		encoded, err := resolver.RegistryAuth(image)
+		if err != nil {
+			t.Fatalf("unexpected error for image '%s': %s", image, err)
+		} else if encoded == "" && expectedAuth != (types.AuthConfig{}) {
+			t.Errorf("expected registry authentication for image '%s'", image)
+		} else if encoded != "" && decodeRegistryAuth(t, encoded) != expectedAuth {
+			t.Errorf("expected registry authentication %+v for image '%s', got %+v", expectedAuth, image, decodeRegistryAuth(t, encoded))
+		}
+	}
+	if redacted := resolver.Redact("hub:password and gcr-token"); redacted != "***** and *****" {
+		t.Errorf("expected secrets resolved from files to be redacted, got '%s'", redacted)
+	}
+}
+
+func TestRegistryAuthFailsOnIllegalDockerConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitzup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dockerConfig := `{"auths": {"gcr.io": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("no-colon")) + `"}}}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(dockerConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	resolver := newRegistryAuthResolver(RegistryAuthConfig{DockerConfigDir: dir})
+	if _, err := resolver.RegistryAuth("gcr.io/project/image"); err == nil {
+		t.Error("expected illegal credentials in Docker configuration to fail")
+	} else if strings.Contains(err.Error(), "no-colon") {
+		t.Errorf("expected error not to leak credentials: %s", err)
+	}
+}
+
+func TestRegistryAuthFromCredentialHelper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitzup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// fake credential helper, answering with a token for "gcr.io" and with credentials for everything else
+	helper := `#!/bin/sh
+read registry
+case "$registry" in
+  gcr.io) echo '{"Username": "<token>", "Secret": "helper-token"}' ;;
+  quay.io) echo 'credentials not found in native keychain'; exit 1 ;;
+  *) echo '{"Username": "helper-user", "Secret": "helper-password"}' ;;
+esac
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "docker-credential-fake"), []byte(helper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dockerConfig := `{"credHelpers": {"gcr.io": "fake", "quay.io": "fake"}, "credsStore": "fake"}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(dockerConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	resolver := newRegistryAuthResolver(RegistryAuthConfig{DockerConfigDir: dir})
+
+	expected := map[string]types.AuthConfig{
+		"gcr.io/project/image": {IdentityToken: "helper-token", ServerAddress: "gcr.io"},
+		"gitzup/test":          {Username: "helper-user", Password: "helper-password", ServerAddress: dockerHubRegistry},
+		"quay.io/team/image":   {},
+	}
+	for image, expectedAuth := range expected {
+		encoded, err := resolver.RegistryAuth(image)
+		if err != nil {
+			t.Fatalf("unexpected error for image '%s': %s", image, err)
+		} else if encoded == "" && expectedAuth != (types.AuthConfig{}) {
+			t.Errorf("expected registry authentication for image '%s'", image)
+		} else if encoded != "" && decodeRegistryAuth(t, encoded) != expectedAuth {
+			t.Errorf("expected registry authentication %+v for image '%s', got %+v", expectedAuth, image, decodeRegistryAuth(t, encoded))
+		}
+	}
+	if redacted := resolver.Redact("helper-token, helper-password"); redacted != "*****, *****" {
+		t.Errorf("expected secrets resolved from credential helpers to be redacted, got '%s'", redacted)
+	}
+}
@@ -2,16 +2,35 @@ package docker
 
 import (
 	"github.com/docker/docker/client"
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/go-errors/errors"
 )
 
-// Docker Client
-var cli *client.Client
+// Configuration of the Docker container runtime.
+type Config struct {
+	// Where to resolve credentials for pulling images from private registries.
+	RegistryAuth RegistryAuthConfig
+}
+
+// Container runtime backed by a Docker daemon.
+type dockerRuntime struct {
+	cli  *client.Client
+	auth *registryAuthResolver
+
+	// Whether to print image pull progress to stdout
+	progress bool
+}
 
-// Initialize the package
-func init() {
+// Creates a container runtime using the Docker daemon configured by the environment (eg. "DOCKER_HOST").
+func NewRuntime(config Config) (build.Runtime, error) {
 	dockerCli, err := client.NewEnvClient()
 	if err != nil {
-		panic(err)
+		return nil, errors.WrapPrefix(err, "failed creating Docker client", 0)
 	}
-	cli = dockerCli
+	// TODO: parametrize "progress"
+	return &dockerRuntime{
+		cli:      dockerCli,
+		auth:     newRegistryAuthResolver(config.RegistryAuth),
+		progress: false,
+	}, nil
 }
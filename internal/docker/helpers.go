@@ -6,38 +6,102 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
+	"time"
 
-	"github.com/docker/docker/api/types/container"
-	"github.com/gitzup/agent/pkg/assets"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/gitzup/agent/pkg/build"
 	"github.com/go-errors/errors"
 )
 
-func CreateJsonResultParser(path string, schema *assets.Schema, response interface{}) ContainerRunHandler {
-	return func(ctx context.Context, c container.ContainerCreateCreatedBody) error {
+func (rt *dockerRuntime) CopyFrom(ctx context.Context, containerID string, path string) ([]byte, error) {
+	reader, _, err := rt.cli.CopyFromContainer(ctx, containerID, path)
+	if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed copying '%s' from container", path), 0)
+	}
+	//noinspection GoUnhandledErrorResult
+	defer reader.Close()
+	tr := tar.NewReader(reader)
+	_, err = tr.Next()
+	if err == io.EOF {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("could not find '%s' in container", path), 0)
+	} else if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed reading '%s' in container", path), 0)
+	}
+	b, err := ioutil.ReadAll(tr)
+	if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed reading '%s' in container", path), 0)
+	}
+	return b, nil
+}
 
-		// read JSON response from resource (fail if missing or invalid)
-		reader, _, err := cli.CopyFromContainer(ctx, c.ID, path)
-		if err != nil {
-			return errors.WrapPrefix(err, fmt.Sprintf("failed copying '%s' from container", path), 0)
-		}
-		//noinspection GoUnhandledErrorResult
-		defer reader.Close()
-		tr := tar.NewReader(reader)
-		_, err = tr.Next()
-		if err == io.EOF {
-			return errors.WrapPrefix(err, fmt.Sprintf("could not find '%s' in container", path), 0)
-		} else if err != nil {
-			return errors.WrapPrefix(err, fmt.Sprintf("failed reading '%s' in container", path), 0)
-		}
-		b, err := ioutil.ReadAll(tr)
-		if err != nil {
-			return errors.WrapPrefix(err, fmt.Sprintf("failed reading '%s' in container", path), 0)
+func (rt *dockerRuntime) CopyArchiveFrom(ctx context.Context, containerID string, path string) (io.ReadCloser, error) {
+	reader, _, err := rt.cli.CopyFromContainer(ctx, containerID, path)
+	if err != nil && isPathNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed copying '%s' from container", path), 0)
+	}
+	return reader, nil
+}
+
+// Returns whether the given error, returned when copying from a container, signals that the path does not exist. The
+// Docker daemon reports missing paths with a generic error, so this relies on its message.
+func isPathNotFound(err error) bool {
+	message := strings.ToLower(err.Error())
+	return client.IsErrNotFound(err) ||
+		strings.Contains(message, "no such file or directory") ||
+		strings.Contains(message, "could not find the file")
+}
+
+func (rt *dockerRuntime) Inspect(ctx context.Context, containerID string) (*build.ContainerInfo, error) {
+	c, err := rt.cli.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, errors.WrapPrefix(err, "failed inspecting container", 0)
+	}
+	info := &build.ContainerInfo{ID: c.ID, Name: strings.TrimPrefix(c.Name, "/")}
+	if c.Config != nil {
+		info.Image = c.Config.Image
+		info.Labels = c.Config.Labels
+	}
+	if created, err := time.Parse(time.RFC3339Nano, c.Created); err == nil {
+		info.Created = created
+	}
+	if c.State != nil {
+		info.Running = c.State.Running
+		info.ExitCode = c.State.ExitCode
+		info.OOMKilled = c.State.OOMKilled
+	}
+	return info, nil
+}
+
+func (rt *dockerRuntime) ListContainers(ctx context.Context) ([]build.ContainerInfo, error) {
+	containers, err := rt.cli.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: managedFilter()})
+	if err != nil {
+		return nil, errors.WrapPrefix(err, "failed listing containers", 0)
+	}
+	var infos []build.ContainerInfo
+	for _, c := range containers {
+		info := build.ContainerInfo{
+			ID:      c.ID,
+			Image:   c.Image,
+			Running: c.State == "running",
+			Labels:  c.Labels,
+			Created: time.Unix(c.Created, 0),
 		}
-		err = schema.ParseAndValidate(response, b)
-		if err != nil {
-			return errors.WrapPrefix(err, fmt.Sprintf("response from container is illegal"), 0)
+		if len(c.Names) > 0 {
+			info.Name = strings.TrimPrefix(c.Names[0], "/")
 		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
 
-		return nil
+func (rt *dockerRuntime) RemoveContainer(ctx context.Context, containerID string) error {
+	err := rt.cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true})
+	if err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed removing container '%s'", containerID), 0)
 	}
+	return nil
 }
@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/go-errors/errors"
+)
+
+func (rt *dockerRuntime) CreateNetwork(ctx context.Context, name string, labels map[string]string) (string, error) {
+	response, err := rt.cli.NetworkCreate(ctx, name, types.NetworkCreate{CheckDuplicate: true, Driver: "bridge", Labels: labels})
+	if err != nil {
+		return "", errors.WrapPrefix(err, fmt.Sprintf("failed creating network '%s'", name), 0)
+	}
+	return response.ID, nil
+}
+
+func (rt *dockerRuntime) ListNetworks(ctx context.Context) ([]build.NetworkInfo, error) {
+	networks, err := rt.cli.NetworkList(ctx, types.NetworkListOptions{Filters: managedFilter()})
+	if err != nil {
+		return nil, errors.WrapPrefix(err, "failed listing networks", 0)
+	}
+	var infos []build.NetworkInfo
+	for _, network := range networks {
+		infos = append(infos, build.NetworkInfo{
+			ID:      network.ID,
+			Name:    network.Name,
+			Labels:  network.Labels,
+			Created: network.Created,
+		})
+	}
+	return infos, nil
+}
+
+func (rt *dockerRuntime) RemoveNetwork(ctx context.Context, networkID string) error {
+	if err := rt.cli.NetworkRemove(ctx, networkID); err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed removing network '%s'", networkID), 0)
+	}
+	return nil
+}
+
+// Returns a filter matching containers & networks created by a Gitzup agent.
+func managedFilter() filters.Args {
+	args := filters.NewArgs()
+	args.Add("label", build.LabelManaged+"=true")
+	return args
+}
@@ -5,13 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
 	. "github.com/gitzup/agent/internal/logger"
+	"github.com/gitzup/agent/pkg/build"
 	"github.com/go-errors/errors"
 )
 
@@ -26,44 +26,120 @@ type dockerPullStatus struct {
 	} `json:"progressDetail"`
 }
 
-func Pull(ctx context.Context, progress bool, image string) error {
+func (rt *dockerRuntime) Pull(ctx context.Context, image string, policy build.PullPolicy) (string, error) {
 
-	// list images
-	imageListArgs := filters.NewArgs()
-	imageListArgs.Add("reference", image)
-	imageList, err := cli.ImageList(ctx, types.ImageListOptions{Filters: imageListArgs})
+	// check if the image is already present
+	present := true
+	if _, _, err := rt.cli.ImageInspectWithRaw(ctx, image); client.IsErrImageNotFound(err) {
+		present = false
+	} else if err != nil {
+		return "", errors.WrapPrefix(err, fmt.Sprintf("failed inspecting image '%s'", image), 0)
+	}
+
+	// pull it if the policy requires it
+	switch build.EffectivePullPolicy(policy, image) {
+	case build.PullAlways:
+		if err := rt.pull(ctx, image); err != nil {
+			return "", err
+		}
+	case build.PullIfNotPresent:
+		if !present {
+			if err := rt.pull(ctx, image); err != nil {
+				return "", err
+			}
+		}
+	case build.PullNever:
+		if !present {
+			return "", errors.Errorf("image '%s' is not present, and pull policy is '%s'", image, build.PullNever)
+		}
+	default:
+		return "", errors.Errorf("unknown pull policy '%s'", policy)
+	}
+
+	return rt.resolveDigest(ctx, image)
+}
+
+// Returns the digest of the given (locally present) image. This is the registry digest for images pulled from a
+// registry, or the image ID for local images.
+func (rt *dockerRuntime) resolveDigest(ctx context.Context, image string) (string, error) {
+	inspect, _, err := rt.cli.ImageInspectWithRaw(ctx, image)
 	if err != nil {
-		return errors.WrapPrefix(err, "failed pulling image", 0)
+		return "", errors.WrapPrefix(err, fmt.Sprintf("failed inspecting image '%s'", image), 0)
 	}
 
-	// if it is already present, return
-	if !strings.HasSuffix(image, ":latest") && len(imageList) > 0 {
-		return nil
+	// prefer the digest of the image's own repository, since the same image may be present in several repositories
+	repository := repositoryOf(image)
+	for _, repoDigest := range inspect.RepoDigests {
+		if tokens := strings.SplitN(repoDigest, "@", 2); len(tokens) == 2 && tokens[0] == repository {
+			return tokens[1], nil
+		}
 	}
+	for _, repoDigest := range inspect.RepoDigests {
+		if tokens := strings.SplitN(repoDigest, "@", 2); len(tokens) == 2 {
+			return tokens[1], nil
+		}
+	}
+	return inspect.ID, nil
+}
 
-	// pull it
+// Returns the repository of the given image reference (ie. without its tag and digest).
+func repositoryOf(image string) string {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image = image[:colon]
+	}
+	return image
+}
+
+// Pulls the given image from its registry.
+func (rt *dockerRuntime) pull(ctx context.Context, image string) error {
+
+	// resolve registry credentials (if any)
+	registryAuth, err := rt.auth.RegistryAuth(image)
+	if err != nil {
+		return errors.Errorf("failed pulling image: %s", rt.auth.Redact(err.Error()))
+	}
+
+	// pull it (making sure credentials never leak into error messages)
 	From(ctx).Infof("Pulling image '%s'...\n", image)
-	reader, err := cli.ImagePull(ctx, image, types.ImagePullOptions{All: true})
+	reader, err := rt.cli.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: registryAuth})
 	if err != nil {
-		return errors.WrapPrefix(err, "failed pulling image", 0)
+		return errors.Errorf("failed pulling image: %s", rt.auth.Redact(err.Error()))
 	}
 	//noinspection GoUnhandledErrorResult
 	defer reader.Close()
 
 	// if we're not using a TTY, ignore output and return
-	if progress {
+	if rt.progress {
 		err = trackProgress(reader)
 		if err != nil {
 			return errors.New(err)
 		}
 		return nil
-	} else if _, err := io.Copy(ioutil.Discard, reader); err != nil {
-		return errors.WrapPrefix(err, "failure occurred while discarding pull progress report", 0)
+	} else if err := discardProgress(reader); err != nil {
+		return errors.Errorf("failed pulling image: %s", rt.auth.Redact(err.Error()))
 	} else {
 		return nil
 	}
 }
 
+// Reads the given pull progress report until it ends, returning the first error it reports (if any).
+func discardProgress(reader io.Reader) error {
+	pullEvents := json.NewDecoder(reader)
+	for {
+		var event dockerPullStatus
+		if err := pullEvents.Decode(&event); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.WrapPrefix(err, "failure occurred while discarding pull progress report", 0)
+		} else if event.Error != "" {
+			return errors.New(event.Error)
+		}
+	}
+}
+
 func trackProgress(reader io.ReadCloser) error {
 	pullEvents := json.NewDecoder(reader)
 	var event *dockerPullStatus
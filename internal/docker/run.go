@@ -4,45 +4,164 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/sirupsen/logrus"
 	"io"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
 	. "github.com/gitzup/agent/internal/logger"
+	"github.com/gitzup/agent/pkg/build"
 	"github.com/go-errors/errors"
 )
 
-type ContainerRunHandler func(ctx context.Context, c container.ContainerCreateCreatedBody) error
+// Grace period given to a container to stop after receiving SIGTERM, before it is killed with SIGKILL.
+var StopGracePeriod = 10 * time.Second
 
+// Grace period given to a container's log stream to be fully consumed once the container stopped (or failed to), before
+// the stream is aborted.
+var logsGracePeriod = 10 * time.Second
+
+// A context carrying the values of its parent (eg. for logging), but which is never done, even when its parent is.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (ctx detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (ctx detachedContext) Done() <-chan struct{}             { return nil }
+func (ctx detachedContext) Err() error                        { return nil }
+func (ctx detachedContext) Value(key interface{}) interface{} { return ctx.parent.Value(key) }
+
+// Prints each line of the given input using the given printer, until the input is exhausted or the context is done.
+// The input is closed when this function returns.
 func printLoop(ctx context.Context, input io.ReadCloser, printer func(logger *logrus.Entry, line string)) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// closing the input unblocks the scanner below
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		if err := input.Close(); err != nil {
+			From(ctx).WithError(err).Warn("failed closing logs stream")
+		}
+	}()
+
 	in := bufio.NewScanner(input)
 	for in.Scan() {
-		// TODO: exit when context is canceled
+		if ctx.Err() != nil {
+			return
+		}
 		printer(From(ctx), in.Text())
-		if err := in.Err(); err != nil {
-			From(ctx).WithError(err).Error("failed copying output to logger")
+	}
+	if err := in.Err(); err != nil && ctx.Err() == nil {
+		From(ctx).WithError(err).Error("failed copying output to logger")
+	}
+}
+
+// Demultiplexes the given container log stream (in Docker's stdcopy format) into stdout & stderr, printing each line to
+// the log and copying the output to the given writers (if any). Returns a channel which is closed once the stream has
+// been fully consumed (ie. the container exited), or the context is done.
+func streamLogs(ctx context.Context, logs io.ReadCloser, stdout io.Writer, stderr io.Writer) <-chan struct{} {
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+
+	var printers sync.WaitGroup
+	printers.Add(2)
+	go func() {
+		defer printers.Done()
+		printLoop(ctx, stdoutReader, func(logger *logrus.Entry, line string) {
+			// event lines are parsed & logged by the build request itself
+			if !strings.HasPrefix(line, build.EventPrefix) {
+				logger.Info(line)
+			}
+		})
+	}()
+	go func() {
+		defer printers.Done()
+		printLoop(ctx, stderrReader, func(logger *logrus.Entry, line string) { logger.Warn(line) })
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := stdcopy.StdCopy(teeWriter(stdout, stdoutWriter), teeWriter(stderr, stderrWriter), logs)
+		if err != nil && ctx.Err() == nil {
+			From(ctx).WithError(err).Warn("failed demultiplexing container output")
+		}
+		if err := logs.Close(); err != nil && ctx.Err() == nil {
+			From(ctx).WithError(err).Warn("failed closing logs stream")
 		}
+		//noinspection GoUnhandledErrorResult
+		stdoutWriter.Close()
+		//noinspection GoUnhandledErrorResult
+		stderrWriter.Close()
+		printers.Wait()
+	}()
+	return done
+}
+
+// Returns a writer duplicating its writes to the given writer (if any) and the given pipe. The given writer goes first,
+// so it receives all output even if the pipe is closed early.
+func teeWriter(writer io.Writer, pipe io.Writer) io.Writer {
+	if writer == nil {
+		return pipe
 	}
+	return io.MultiWriter(writer, pipe)
 }
 
-func Run(
-	ctx context.Context,
-	image string,
-	containerName string,
-	env []string,
-	volumes map[string]struct{},
-	input interface{},
-	preExitHandler ContainerRunHandler,
-	postExitHandler ContainerRunHandler) *errors.Error {
+// Translates the given limits to Docker container resources.
+func resources(limits build.Limits) container.Resources {
+	resources := container.Resources{
+		NanoCPUs:  int64(limits.CPUs * 1e9),
+		Memory:    limits.Memory,
+		PidsLimit: limits.PIDs,
+	}
+	if limits.Memory > 0 {
+		// disallow swapping beyond the memory limit
+		resources.MemorySwap = limits.Memory
+	}
+	return resources
+}
 
-	// work with a child context which has the "containerName" key
-	ctx = context.WithValue(ctx, "container", containerName)
+// Translates the given mounts to Docker bind specifications ("<source>:<target>[:ro]").
+func binds(mounts []build.Mount) []string {
+	var binds []string
+	for _, mount := range mounts {
+		bind := mount.Source + ":" + mount.Target
+		if mount.ReadOnly {
+			bind += ":ro"
+		}
+		binds = append(binds, bind)
+	}
+	return binds
+}
+
+// Invokes the given handler in a separate goroutine, and waits for it to return. If the context is done first, the
+// given interrupt function (if any) is invoked to unblock the handler (eg. by stopping its container), and the handler
+// is still waited for, so it never outlives the container; the context's error is then returned.
+func runHandler(ctx context.Context, handler build.ContainerHandler, containerID string, interrupt func()) error {
+	result := make(chan error, 1)
+	go func() { result <- handler(ctx, containerID) }()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		if interrupt != nil {
+			interrupt()
+		}
+		<-result
+		return ctx.Err()
+	}
+}
 
-	// create container
-	c, err := cli.ContainerCreate(
+// Creates a container according to the given specification.
+func (rt *dockerRuntime) createContainer(ctx context.Context, spec *build.ContainerSpec) (container.ContainerCreateCreatedBody, error) {
+	return rt.cli.ContainerCreate(
 		ctx,
 		&container.Config{
 			Domainname:   "gitzup.local",
@@ -51,38 +170,101 @@ func Run(
 			AttachStderr: true,
 			OpenStdin:    true,
 			StdinOnce:    true,
-			Tty:          true,
-			Env:          env,
-			Image:        image,
-			Volumes:      volumes,
+			Tty:          false,
+			Env:          spec.Env,
+			Image:        spec.Image,
+			Entrypoint:   spec.Entrypoint,
+			Cmd:          spec.Cmd,
+			Volumes:      spec.Volumes,
+			User:         spec.User,
+			Labels:       spec.Labels,
+		},
+		&container.HostConfig{
+			AutoRemove:  false,
+			Binds:       binds(spec.Mounts),
+			NetworkMode: container.NetworkMode(spec.Network),
+			Resources:   resources(spec.Limits),
 		},
-		&container.HostConfig{AutoRemove: false},
 		nil,
-		containerName)
+		spec.Name)
+}
+
+// Returns whether the given error, returned when creating a container, signals that its name is already in use. The
+// Docker daemon reports conflicts with a generic error, so this relies on its message.
+func isNameConflict(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "is already in use")
+}
+
+// Removes the existing container with the name of the given specification, if it is stale (see build.IsStaleContainer);
+// fails otherwise, since the container may still be in use.
+func (rt *dockerRuntime) removeStaleContainer(ctx context.Context, spec *build.ContainerSpec) error {
+	info, err := rt.Inspect(ctx, spec.Name)
+	if err != nil {
+		return err
+	} else if !build.IsStaleContainer(*info, spec) {
+		return errors.Errorf("failed creating container: name '%s' is already in use", spec.Name)
+	}
+
+	From(ctx).Warnf("Removing stale container '%s' (ID '%s')", spec.Name, info.ID)
+	return rt.RemoveContainer(ctx, info.ID)
+}
+
+// Stops the given container, giving it the stop grace period to exit after SIGTERM before killing it with SIGKILL.
+func (rt *dockerRuntime) stopContainer(ctx context.Context, containerID string) {
+	// the given context is most likely done already, so use a separate context for stopping the container
+	stopCtx, cancel := context.WithTimeout(context.Background(), StopGracePeriod+10*time.Second)
+	defer cancel()
+
+	gracePeriod := StopGracePeriod
+	From(ctx).Warnf("Stopping container '%s' (grace period is %s)", containerID, gracePeriod)
+	if err := rt.cli.ContainerStop(stopCtx, containerID, &gracePeriod); err != nil {
+		From(ctx).WithError(err).Warnf("Failed stopping container '%s'; will now use SIGKILL", containerID)
+		if err := rt.cli.ContainerKill(stopCtx, containerID, "SIGKILL"); err != nil {
+			From(ctx).WithError(err).Errorf("Failed killing container '%s'", containerID)
+		}
+	}
+}
+
+func (rt *dockerRuntime) Run(ctx context.Context, spec *build.ContainerSpec, preExitHandler build.ContainerHandler, postExitHandler build.ContainerHandler) error {
+
+	// work with a child context which has the "containerName" key
+	ctx = context.WithValue(ctx, "container", spec.Name)
+
+	// create container, replacing a stale container of the same name (eg. left behind by a crashed agent) if necessary
+	c, err := rt.createContainer(ctx, spec)
+	if err != nil && isNameConflict(err) {
+		if err := rt.removeStaleContainer(ctx, spec); err != nil {
+			return err
+		}
+		c, err = rt.createContainer(ctx, spec)
+	}
 	if err != nil {
 		return errors.WrapPrefix(err, "failed creating container", 0)
 	}
 	defer func() {
-		if err := cli.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{}); err != nil {
+		// the given context may have already expired (eg. timed out), so use a separate context for cleanup
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := rt.cli.ContainerRemove(cleanupCtx, c.ID, types.ContainerRemoveOptions{Force: true}); err != nil {
 			From(ctx).WithError(err).Warnf("failed removing container ID '%s'", c.ID)
 		}
 	}()
 
 	// start container
-	if err := cli.ContainerStart(ctx, c.ID, types.ContainerStartOptions{}); err != nil {
+	if err := rt.cli.ContainerStart(ctx, c.ID, types.ContainerStartOptions{}); err != nil {
 		return errors.WrapPrefix(err, "failed starting container", 0)
 	}
 
 	// if input provided, attach to container and send the input to stdin
-	if input != nil {
-		resp, err := cli.ContainerAttach(ctx, c.ID, types.ContainerAttachOptions{Stream: true, Stdin: true})
+	if spec.Input != nil {
+		resp, err := rt.cli.ContainerAttach(ctx, c.ID, types.ContainerAttachOptions{Stream: true, Stdin: true})
 		if err != nil {
 			return errors.WrapPrefix(err, "failed attaching to container", 0)
 		}
 		defer resp.Close()
 
 		// send input to the container's stdin
-		b, err := json.Marshal(input)
+		b, err := json.Marshal(spec.Input)
 		if err != nil {
 			return errors.WrapPrefix(err, "failed serializing request input to JSON", 0)
 		}
@@ -98,79 +280,80 @@ func Run(
 		}
 	}
 
-	// stream logs to our stdout
-	stdout, err := cli.ContainerLogs(ctx, c.ID, types.ContainerLogsOptions{
+	// the log stream outlives the given context, so the output of a stopped container (eg. on timeout) is still captured
+	logsCtx, cancelLogs := context.WithCancel(detachedContext{ctx})
+	defer cancelLogs()
+
+	// stream logs to our log & to the spec's writers (without a TTY, stdout & stderr are multiplexed in one stream)
+	logs, err := rt.cli.ContainerLogs(logsCtx, c.ID, types.ContainerLogsOptions{
 		ShowStdout: true,
-		ShowStderr: false,
+		ShowStderr: true,
 		Follow:     true,
 	})
-	defer func() {
-		err := stdout.Close()
-		if err != nil {
-			From(ctx).WithError(err).Warnf("failed closing stdout logs streams")
-		}
-	}()
 	if err != nil {
-		return errors.WrapPrefix(err, "failed fetching stdout log from container", 0)
+		return errors.WrapPrefix(err, "failed fetching logs from container", 0)
 	}
-	go printLoop(ctx, stdout, func(logger *logrus.Entry, line string) { logger.Info(line) })
+	logsDone := streamLogs(logsCtx, logs, spec.Stdout, spec.Stderr)
 
-	// stream logs to our stderr
-	stderr, err := cli.ContainerLogs(ctx, c.ID, types.ContainerLogsOptions{
-		ShowStdout: false,
-		ShowStderr: true,
-		Follow:     true,
-	})
-	defer func() {
-		err := stderr.Close()
-		if err != nil {
-			From(ctx).WithError(err).Warnf("failed closing stderr logs streams")
+	// on every path, wait for the log stream to be consumed (it ends once the container exits), so the spec's writers
+	// are never written to after returning; a stream that does not end in time is aborted
+	waitForLogs := func() {
+		select {
+		case <-logsDone:
+		case <-time.After(logsGracePeriod):
+			From(ctx).Warnf("Log stream of container '%s' did not end in time; aborting it", c.ID)
+			cancelLogs()
+			<-logsDone
 		}
-	}()
-	if err != nil {
-		return errors.WrapPrefix(err, "failed fetching stderr log from container", 0)
 	}
-	go printLoop(ctx, stderr, func(logger *logrus.Entry, line string) { logger.Warn(line) })
+	defer waitForLogs()
 
 	// if pre-exit handler provided, invoke it now
-	// TODO: run handler in goroutine, and abort when context is canceled
 	if preExitHandler != nil {
-		// handle the container
-		err = preExitHandler(ctx, c)
-		if err != nil {
+		// handle the container (stopping it if the context is done first, since the handler may be waiting on it)
+		stopped := false
+		err = runHandler(ctx, preExitHandler, c.ID, func() {
+			rt.stopContainer(ctx, c.ID)
+			stopped = true
+		})
+		if err != nil && !stopped {
+			rt.stopContainer(ctx, c.ID)
+		}
+		if err != nil && ctx.Err() != nil {
+			return errors.WrapPrefix(ctx.Err(), "container stopped", 0)
+		} else if err != nil {
 			return errors.WrapPrefix(err, "failed invoking pre-exit callback on container", 0)
 		}
 	}
 
-	// wait for container to finish
-	ctx30Sec, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if _, err := cli.ContainerWait(ctx30Sec, c.ID); err != nil {
-		From(ctx).WithError(err).Warnf("Killing container '%s' (SIGTERM)", c.ID)
-		if err := cli.ContainerKill(ctx, c.ID, "SIGTERM"); err != nil {
-			From(ctx).WithError(err).Warnf("Failed killing container '%s' using SIGTERM; will now use SIGKILL", c.ID)
-			if err := cli.ContainerKill(ctx, c.ID, "SIGKILL"); err != nil {
-				From(ctx).WithError(err).Errorf("Failed killing container '%s' using both SIGTERM and SIGKILL", c.ID)
-			}
+	// wait for container to finish, for as long as the given context allows
+	if _, err := rt.cli.ContainerWait(ctx, c.ID); err != nil {
+		rt.stopContainer(ctx, c.ID)
+		if ctx.Err() != nil {
+			return errors.WrapPrefix(ctx.Err(), "container stopped", 0)
 		}
-		time.Sleep(2 * time.Second)
 		return errors.WrapPrefix(err, "failed waiting for container", 0)
 	}
-	cntr, err := cli.ContainerInspect(ctx, c.ID)
+
+	// the log stream ends once the container exits; wait for it to be fully consumed, so the output is complete
+	waitForLogs()
+	info, err := rt.Inspect(ctx, c.ID)
 	if err != nil {
-		return errors.WrapPrefix(err, "failed inspecting container", 0)
+		return err
 	}
-	exitCode := cntr.State.ExitCode
-	if exitCode != 0 {
-		return errors.WrapPrefix(err, fmt.Sprintf("container terminated with exit-code %d", exitCode), 0)
+	if info.OOMKilled {
+		return build.OOMKilledError(spec.Limits.Memory)
+	} else if info.ExitCode != 0 {
+		return errors.Errorf("container terminated with exit-code %d", info.ExitCode)
 	}
 
 	// if post-exit handler provided, invoke it now
-	// TODO: run handler in goroutine, and abort when context is canceled
 	if postExitHandler != nil {
 		// handle the container
-		err = postExitHandler(ctx, c)
-		if err != nil {
+		err = runHandler(ctx, postExitHandler, c.ID, nil)
+		if err != nil && ctx.Err() != nil {
+			return errors.WrapPrefix(ctx.Err(), "container stopped", 0)
+		} else if err != nil {
 			return errors.WrapPrefix(err, "failed invoking post-exit callback on container", 0)
 		}
 	}
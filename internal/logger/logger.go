@@ -3,13 +3,21 @@ package logger
 import (
 	"context"
 	log "github.com/sirupsen/logrus"
+	"io"
 	golog "log"
 	"os"
+	"sync"
 	"time"
 )
 
 var root *log.Entry
 
+// Loggers returned by LoggerWithLevel, by log level; reset whenever the root logger's configuration changes.
+var levelLoggers = struct {
+	sync.Mutex
+	entries map[log.Level]*log.Entry
+}{entries: make(map[log.Level]*log.Entry)}
+
 // Initialize the logging infrastructure.
 func InitLogger(version string, caller bool, logLevel string, logFormat string) {
 	log.SetOutput(os.Stdout)
@@ -73,21 +81,59 @@ func InitLogger(version string, caller bool, logLevel string, logFormat string)
 	root = log.WithFields(log.Fields{
 		"version": version,
 	})
+	resetLevelLoggers()
 
 	// redirect Golang standard log package output to logrus
 	golog.SetFlags(0)
 	golog.SetOutput(Logger().Writer())
 }
 
+// Returns the root logger. Before InitLogger is invoked (eg. in tests, or when the agent is used as a library), this is
+// logrus' standard logger with its default settings.
 func Logger() *log.Entry {
 	if root == nil {
-		panic("logger has not been set!")
+		return log.NewEntry(log.StandardLogger())
 	}
 	return root
 }
 
+// Returns a logger which behaves like the root logger, except for its minimum log level. Place it in a context under
+// the "logger" key to override the log level of everything logged with that context. A single logger is created per
+// log level, and shared by all callers.
+func LoggerWithLevel(level log.Level) *log.Entry {
+	levelLoggers.Lock()
+	defer levelLoggers.Unlock()
+	if entry, ok := levelLoggers.entries[level]; ok {
+		return entry
+	}
+
+	std := log.StandardLogger()
+	logger := log.New()
+	logger.Out = std.Out
+	logger.Formatter = std.Formatter
+	logger.Hooks = std.Hooks
+	logger.ReportCaller = std.ReportCaller
+	logger.SetLevel(level)
+	entry := logger.WithFields(Logger().Data)
+	levelLoggers.entries[level] = entry
+	return entry
+}
+
+// Discards the loggers created by LoggerWithLevel, so they are re-created with the root logger's new configuration.
+func resetLevelLoggers() {
+	levelLoggers.Lock()
+	defer levelLoggers.Unlock()
+	levelLoggers.entries = make(map[log.Level]*log.Entry)
+}
+
 func From(ctx context.Context) *log.Entry {
 	var logger = Logger()
+	if contextLogger, ok := ctx.Value("logger").(*log.Entry); ok {
+		logger = contextLogger
+	}
+	if correlationId, ok := ctx.Value("correlation").(string); ok {
+		logger = logger.WithField("correlation", correlationId)
+	}
 	if requestId, ok := ctx.Value("request").(string); ok {
 		logger = logger.WithField("request", requestId)
 	}
@@ -99,3 +145,9 @@ func From(ctx context.Context) *log.Entry {
 	}
 	return logger
 }
+
+// Redirects log output to the given writer (stdout by default).
+func SetOutput(w io.Writer) {
+	log.SetOutput(w)
+	resetLevelLoggers()
+}
@@ -1,11 +1,20 @@
 // Code generated by go-bindata. DO NOT EDIT.
 // sources:
-// api/schema/action.json (654B)
+// api/schema/action.json (1619B)
+// api/schema/apply.request.json (1612B)
+// api/schema/apply.response.json (750B)
 // api/schema/build.request.json (673B)
-// api/schema/build.response.json (264B)
+// api/schema/build.response.json (8721B)
+// api/schema/change.json (1060B)
+// api/schema/event.json (2051B)
 // api/schema/init.request.json (896B)
-// api/schema/init.response.json (529B)
-// api/schema/resource.json (685B)
+// api/schema/init.response.json (1365B)
+// api/schema/limits.json (917B)
+// api/schema/plan.request.json (1344B)
+// api/schema/plan.response.json (625B)
+// api/schema/resource.json (2651B)
+// api/schema/state.request.json (2760B)
+// api/schema/state.response.json (891B)
 
 package assets
 
@@ -74,7 +83,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _schemaActionJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x8f\xcd\x4e\xeb\x30\x10\x85\xf7\x79\x0a\xcb\xb7\x52\x2f\x82\xc6\xb0\x42\xf2\xa6\xaf\x80\xd8\x56\xad\x64\xec\x69\xea\x2a\xc9\x98\xf1\x04\x29\x54\x79\x77\xe4\xfc\x94\xb4\x94\x2d\x9b\x28\x3e\xfe\xbe\x99\xe3\x53\x26\x84\x10\x72\x11\xed\x01\x2a\x23\xb5\x90\x07\xe6\xa0\x95\x3a\x46\xac\x57\x43\x9a\x23\x15\xca\x91\xd9\xf3\xea\xf1\x59\x0d\xd9\x3f\xf9\x30\x9a\xde\xcd\xac\xc2\xf3\x67\x13\x72\x8b\xd5\xc8\xa9\x8f\x27\x65\x2c\x7b\xac\xf3\x34\x71\xb2\x1c\x44\x4b\x3e\xa4\x3c\xd9\xaf\x10\xb1\x21\x0b\x22\x10\x32\x5a\x2c\xc5\x72\x90\x96\xf9\x64\x70\x1b\x20\xa1\xf8\x76\x04\xcb\x53\x6a\x9c\xf3\x89\x33\xe5\x0b\x61\x00\x62\x0f\x51\x6a\xb1\x37\x65\x84\x11\x21\x78\x6f\x3c\x41\x6a\xb9\xe9\x93\x3e\xf5\x95\x29\x40\xf6\xe7\xed\x08\x86\xf9\x84\xd3\x35\x3a\x8f\x2e\x0a\x45\x26\x5f\x17\x63\xa1\xf3\x6d\x30\xcc\x40\xfd\xe3\x76\x9b\x9d\xde\xde\xff\x5f\x6b\xdd\xff\xdc\xad\x17\xf2\xcc\x76\xdf\x9a\x84\x9a\xa9\x0d\xe8\x6b\xfe\x7d\x99\x21\x32\xed\xf5\x2e\xcf\x50\xc5\x1f\xce\xad\x92\x17\x40\x77\xb3\x85\xad\xdc\x9f\xaf\xcf\x86\x6f\x97\x7d\x05\x00\x00\xff\xff\x0d\x2d\x74\xce\x8e\x02\x00\x00")
+var _schemaActionJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xc5\x54\xc1\x6e\xdb\x30\x0c\xbd\xf7\x2b\x08\xad\x40\xe2\x36\xb6\x93\x6e\x6b\x51\x5f\xb2\x01\xbb\x76\x18\x76\x5c\xed\x15\x8a\xc5\x38\x6a\x6d\xc9\x93\xe4\x6e\x6e\x92\x7f\x9f\x24\xdb\x59\xda\x06\x68\x87\x1d\xe6\x83\x20\x92\xef\x51\xd4\xb3\xc8\xf5\x11\xd8\x8f\x1c\xeb\x7c\x85\x15\x25\x09\x90\x95\x31\x75\x12\xc7\xb7\x5a\x8a\xb0\xf3\x46\x52\x15\x31\x53\x74\x69\xc2\xe9\x45\xdc\xf9\xde\x90\x49\xcf\xe4\x6c\x8f\x55\x70\xf3\xd0\xd4\x51\x2e\xab\x1e\x17\xdf\xcf\x62\x9a\x1b\x2e\x45\xe4\x32\x0e\x2c\x86\x3a\x57\xbc\x76\x7e\xc7\xfe\x8a\x5a\x36\x2a\x47\xa8\x95\x34\x32\x97\x25\x8c\x3a\xd2\x28\x1a\x18\xa6\xad\xd1\x41\xe5\xe2\x16\x73\x33\x78\x29\x63\xdc\xe1\x68\xf9\x45\xc9\x1a\x95\xe1\xa8\x2d\x6a\x49\x4b\x8d\x3d\x44\xe1\x8f\x86\x2b\x74\x55\x5e\x7b\x8f\xf7\xf2\x8a\x16\x48\xbc\x9d\xf5\xc0\x7a\x3f\xc3\xfa\x29\x74\xdf\x75\xe8\x0a\x9f\x64\x7e\x87\x0a\x3c\x18\x14\x2e\x51\xa1\xc8\x71\x02\xb2\xee\xea\x2b\x5b\xe0\x22\x2f\x1b\xc6\x45\x01\x14\x0c\xb5\xab\x60\xb1\x54\xd6\x60\xbc\x40\x6d\x60\x8c\x45\x04\x29\xe9\x34\x8c\x8b\xbc\x0e\x6d\x49\xee\xb6\xc9\x2c\x9a\xa6\x04\x2c\xd6\x46\x73\x15\x71\x19\x57\xed\x10\x74\x5b\x7f\xea\x07\xbd\xa2\x67\xef\xcf\x93\x28\x8a\x52\x12\x0c\xc2\xed\xea\x1d\x04\xd4\x46\xd9\x12\x9e\x46\x6b\x6a\x0c\x2a\x7f\x93\xef\xe3\x79\x72\x4d\xc3\x87\x8f\xe1\xb7\x69\x78\x99\x3d\xb2\xc2\xec\x64\x2f\x14\xcc\x6d\x30\x4d\xa3\xd7\xa2\x83\x13\x1b\x4e\xae\x9d\x71\x1a\xcc\xe3\x60\xee\xa2\xde\xb2\x7e\xc7\x8c\x6e\xb2\xcd\xcd\xcd\x26\x3c\x0d\x76\x11\xcf\x89\x5f\x05\xec\xd3\xa7\xe9\xcf\xcc\x2d\x51\x98\xad\xa7\x93\xd9\xd9\xc5\xd6\xd7\x39\xc8\x63\x09\x4b\x47\x58\x9f\xbf\xb3\x81\x63\xb2\xd3\x61\xfb\x47\x12\x82\xc2\xa8\xb6\x96\x5c\x98\xe7\x3f\x7e\x10\x92\x2a\x45\xdb\xa7\x3a\x72\x83\x95\x7e\xc6\x39\xf4\x03\x1e\x01\xb6\x07\xab\xc8\x2b\xf6\x3f\x8f\x17\x68\x7e\x4a\x75\xf7\xe2\xd3\xff\xdc\xe1\xa0\x92\x0c\x41\x2e\xc1\xac\x10\xba\xf6\x4d\x60\xb4\x68\x78\xc9\x46\x30\x76\x4e\x86\x4b\xda\x94\x26\x00\xfb\xd8\xa8\x1d\x0f\x7a\x0f\x0a\x46\x7a\xcb\xe3\xc1\x35\xad\x6d\x89\x91\x86\xbe\x88\x89\xeb\x16\x18\x09\x29\x70\x64\xfb\x45\xd3\x45\x89\xbb\xa0\xbd\xcf\x5f\x3e\x77\x14\x4d\xe5\x06\x02\x10\x7f\x1e\x99\xd8\xdb\xda\xd4\x04\xb2\x83\x4a\x18\x5e\xa1\x6c\xcc\x8b\x4a\x5c\xd1\x5f\xbc\x6a\x2a\x60\x8d\xa2\xfe\x52\x8f\xd4\x18\x1a\xfc\x72\xaa\x53\x7b\x62\x4a\x66\xd3\xaa\xdf\xac\xde\xba\x6d\x10\xc1\x15\x6d\x61\x61\x55\xbc\x47\xa5\x38\x63\x28\x60\xd1\xfa\x14\xaa\x1f\x91\x56\x92\x74\xa8\xc7\xa6\x81\x7e\x6a\xb9\xe9\xf2\x5c\xbf\x7f\x19\x02\x5d\x9b\x8e\x5d\x7b\xf7\x0d\x3b\x16\x7a\xd3\xe8\x4d\xa5\x37\x76\xd9\xac\x82\xe0\x74\xbf\x7b\x8e\xba\x75\x7b\xf4\x1b\x65\xbb\x93\xfd\x53\x06\x00\x00")
 
 func schemaActionJsonBytes() ([]byte, error) {
 	return bindataRead(
@@ -89,8 +98,48 @@ func schemaActionJson() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "schema/action.json", size: 654, mode: os.FileMode(420), modTime: time.Unix(1541190689, 0)}
-	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xdd, 0x2a, 0xf6, 0x7e, 0xee, 0x47, 0xf7, 0x34, 0x2d, 0x1e, 0xc4, 0xac, 0x78, 0x9a, 0x39, 0x6b, 0x98, 0xff, 0x47, 0x98, 0x6a, 0x33, 0x79, 0x8a, 0xed, 0xd4, 0x5b, 0x98, 0xcd, 0x6d, 0xca, 0xef}}
+	info := bindataFileInfo{name: "schema/action.json", size: 1619, mode: os.FileMode(420), modTime: time.Unix(1792197210, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x3e, 0xf8, 0xee, 0x3a, 0x5a, 0xf7, 0x6, 0xd, 0x49, 0x1, 0x41, 0xc2, 0x68, 0xbe, 0x65, 0x3d, 0xf5, 0x42, 0x70, 0x15, 0xba, 0x3c, 0xee, 0x7c, 0xcf, 0x24, 0x62, 0x2, 0x7f, 0xc2, 0xda, 0xa4}}
+	return a, nil
+}
+
+var _schemaApplyRequestJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa5\x54\x4d\x6f\xdb\x30\x0c\xbd\xe7\x57\x08\x5e\x81\x1c\x16\xc7\xcd\x69\x58\x6f\xc3\x4e\x05\x76\x18\x7a\x6c\x90\x83\x62\xd3\xb6\x0a\x5b\xd2\x28\xba\x40\x5a\xe4\xbf\x4f\x92\x3f\xe2\xcf\xba\x40\x7d\x30\x64\x92\xef\x91\x4f\x26\xf9\xbe\x61\xf6\x09\xee\x4c\x9c\x43\xc9\x83\x07\x16\xe4\x44\xfa\x21\x8a\x5e\x8c\x92\x61\x6d\xdd\x2b\xcc\xa2\x04\x79\x4a\xe1\xfd\x8f\xa8\xb6\x7d\x0b\x76\x0d\x52\x24\x3d\x54\x26\xe8\xad\xd2\xfb\x58\x95\x4d\x5c\xf4\x7a\x88\xb8\xd6\xc5\x65\x8f\xf0\xaf\x02\x43\x7b\x47\xdc\x82\x13\x30\x31\x0a\x4d\xc2\x9a\x2c\xc9\x13\x18\x55\x61\x0c\x4c\xa3\x22\x15\xab\x82\x6d\x3d\x76\xcb\x5a\x70\x0b\xa4\x8b\x06\x87\x50\xe7\x17\x88\xa9\xb5\xf2\x24\x11\x8e\x8b\x17\x7f\x51\x69\x40\x12\x60\x6c\x54\xca\x0b\x03\x4d\x88\x23\x12\x08\xae\xe6\xa3\xb7\x74\x56\x4b\xff\x98\x04\x3b\xf7\x51\x57\xe1\xce\x86\x38\xf9\x43\x9c\x73\x99\x59\x36\x8f\x39\x35\x64\xba\x9f\xe5\x7d\x8e\xae\x6f\x1e\x14\x6e\x08\x85\xcc\x9a\xc2\x3b\x6f\x29\xe4\x1f\x90\x19\xe5\x36\xe4\xd0\xb9\xae\xbb\x3e\x75\x53\xdc\x22\xf3\xe0\x4a\x3a\xef\xfa\xd5\x0c\xaa\x9f\x5c\x51\xe7\x95\xbc\x84\x11\xf9\x2d\xfd\xc0\x7c\x1a\xf1\x2e\xdc\xd6\x90\x79\xce\xb3\x7e\x71\xb7\x1c\x9c\x08\xd0\x37\xd3\x91\x87\x6f\xa7\x63\x68\xdf\xbf\xc2\xe7\xfb\xf0\xe7\xe9\x7b\x30\xc1\x5c\x97\x84\x7c\xb1\x8c\xf9\xff\xf8\x51\xd6\x58\xc9\x54\x64\xcb\x79\x97\x26\xa5\xc6\x55\xc8\x9d\x67\xc7\xb8\x71\xb3\xf3\x2a\x12\x48\x98\x90\x8c\x72\x60\xe7\x4a\x14\xc9\x78\x80\x16\x75\xcd\x76\xcf\x5a\x17\x11\x56\x30\x55\xb9\x99\xff\xea\x77\x73\x3d\x5e\x93\x56\x1e\x89\xfd\x5d\x21\x82\x24\xe6\xa3\x99\x4a\xbd\xac\x76\x10\xbc\x66\x04\xaa\x50\x5a\xcd\xe7\x8b\x77\x6e\x7d\xe8\x96\xf1\xd8\x71\x8c\x45\xb7\x62\x8f\x37\xb9\xb6\xfb\xaa\xa2\x08\x26\x3d\xfb\x19\xc1\x7d\x41\xed\x9a\x58\x95\x54\xc7\x31\x52\xcc\x6f\x38\x77\xe8\xab\x5a\x2a\x39\xe0\x88\xfc\x32\xb3\x36\x1e\x09\x4a\x97\xf6\x30\x72\x89\xc6\x3e\x33\x6f\x77\x08\xe9\xda\xea\xae\xf5\xd4\x3b\x7b\xe9\x7f\x6e\xea\xf7\x75\xf3\x1f\xf3\xc0\xf7\x20\x4c\x06\x00\x00")
+
+func schemaApplyRequestJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_schemaApplyRequestJson,
+		"schema/apply.request.json",
+	)
+}
+
+func schemaApplyRequestJson() (*asset, error) {
+	bytes, err := schemaApplyRequestJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "schema/apply.request.json", size: 1612, mode: os.FileMode(420), modTime: time.Unix(1792195963, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x8b, 0x16, 0xff, 0x55, 0xcd, 0xb7, 0xb1, 0x98, 0x51, 0x53, 0xac, 0x96, 0xd5, 0xb9, 0x25, 0xd7, 0x18, 0x4, 0x11, 0xd5, 0x2f, 0x0, 0x51, 0xef, 0xd5, 0x88, 0xa, 0xcd, 0x85, 0xdf, 0xbe, 0x7d}}
+	return a, nil
+}
+
+var _schemaApplyResponseJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x95\x91\xdd\x4a\x03\x31\x10\x85\xef\xfb\x14\x21\x16\xaa\x60\xb3\x7a\x25\x94\xd2\x67\x10\x05\x6f\x5a\x2f\xd2\xec\xec\x36\x65\x9b\x89\x93\x89\x5a\x4b\xdf\xdd\xec\x6f\x2d\x58\xc4\xbd\x08\x9b\x8f\x73\x86\x73\x26\x87\x91\x48\x9f\x1c\x07\xb3\x81\x9d\x96\x33\x21\x37\xcc\x7e\x96\x65\xdb\x80\x6e\xda\x52\x85\x54\x66\x39\xe9\x82\xa7\x77\x0f\x59\xcb\xae\xe4\x6d\xe7\xb4\xf9\x0f\x57\x69\xf9\x2b\x7a\x65\x70\xd7\xe9\xb2\xf7\xfb\x4c\x7b\x5f\xed\x15\x41\xf0\xe8\x02\xa8\x7a\x72\xef\xce\x21\x18\xb2\x9e\x6d\x42\x69\xca\x13\x04\x8c\x64\x40\x78\x42\x46\x83\x95\x98\x34\xe6\x89\x18\xdc\xbd\x93\xf7\x1e\x6a\x0b\xae\xb7\x60\xb8\xa7\x3a\xcf\x6d\x3d\x4c\x57\x8f\x84\x1e\x88\x2d\x84\xa4\x2a\x74\x15\xa0\x93\x10\xbc\x45\x4b\x50\xa7\x5e\x8a\xd7\x0e\xfa\x9f\xea\x43\xc3\x1a\x1e\x58\x33\x9c\xa1\xdf\x62\x3f\xd7\x2a\x81\x85\xe0\x0d\xd4\x49\xdb\x0e\x69\x5f\x40\x0d\x32\x1b\xed\x4a\x08\xe2\x03\x28\xe1\x54\xc8\x42\xde\x17\x19\x66\x76\x85\x96\xa7\x4a\x42\xba\x58\x55\xb2\x0f\x39\x28\x2f\x94\x64\x8a\x30\x08\x8f\x27\x8f\xc4\xc8\x3e\x72\xf8\xb3\xc6\x8b\xae\x62\x4a\x09\x9f\x1e\x03\xe4\x62\xbd\x3f\xef\x53\x20\x09\x4c\x84\x06\x14\x04\x63\xba\x14\xa9\x96\x4b\x02\xeb\x6a\x83\x25\x61\xd0\x15\xb6\x8c\xa4\xeb\xc9\xe2\x1a\x4a\x25\x56\x72\x7c\x18\x6c\x6a\xee\xf4\x0e\x16\xaa\x0b\xa6\xe6\xed\xcf\xe2\xb8\x92\x37\x97\xf6\x72\xfe\xd0\xff\xdb\xc5\xa8\x3d\x8f\xa3\x6f\xf2\xc6\x4f\x3f\xee\x02\x00\x00")
+
+func schemaApplyResponseJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_schemaApplyResponseJson,
+		"schema/apply.response.json",
+	)
+}
+
+func schemaApplyResponseJson() (*asset, error) {
+	bytes, err := schemaApplyResponseJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "schema/apply.response.json", size: 750, mode: os.FileMode(420), modTime: time.Unix(1792196221, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xf3, 0x8b, 0x19, 0x20, 0x28, 0x83, 0x19, 0x32, 0xd4, 0x82, 0x75, 0x3c, 0xdb, 0x3f, 0xd0, 0x6f, 0x7e, 0x16, 0xff, 0x16, 0xf7, 0x65, 0x59, 0x5c, 0x97, 0xce, 0xf3, 0xb9, 0x6b, 0x8c, 0x66, 0xda}}
 	return a, nil
 }
 
@@ -114,7 +163,7 @@ func schemaBuildRequestJson() (*asset, error) {
 	return a, nil
 }
 
-var _schemaBuildResponseJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x4c\xce\x41\x4e\x85\x30\x10\xc6\xf1\x3d\xa7\x98\x54\x97\xd2\xea\xca\x84\x9d\x37\x70\x6f\x5c\x94\x76\x80\x21\x40\xeb\x74\x30\x51\xc2\xdd\x5f\xfa\x28\x2f\x6c\xbf\xfc\xfe\x93\xd9\x2a\x00\x00\xf5\x9c\xdc\x80\xb3\x55\x0d\xa8\x41\x24\x36\xc6\x8c\x29\x2c\xf5\xb1\xea\xc0\xbd\xf1\x6c\x3b\xa9\x5f\xdf\xcd\xb1\x3d\xa9\x97\x52\x92\xbf\x54\x3d\xc9\xff\x1a\xb5\x0b\x73\x71\xe6\xf7\xcd\xb4\x2b\x4d\x5e\x33\xa6\x18\x96\x84\x3a\x5f\x3e\x6b\x8f\xc9\x31\x45\xa1\xb0\xe4\x2b\x1f\x70\xb7\xf0\xb0\xa7\x93\xbf\x88\x19\x84\x76\x44\x27\xe7\x6a\xbd\xa7\x9c\xda\xe9\x93\x43\x44\x16\xc2\xa4\x1a\xe8\xec\x94\xb0\x10\xc6\x9f\x95\x18\xf3\x8f\x5f\xf0\x5d\xc6\x78\xd5\x1b\xec\xd5\x5e\xdd\x02\x00\x00\xff\xff\xac\x3d\x97\x59\x08\x01\x00\x00")
+var _schemaBuildResponseJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcd\x5a\x51\x6f\xdb\x36\x10\x7e\xcf\xaf\x20\xdc\x02\x4e\x00\x3b\x6e\x87\xad\xc3\x8a\xa2\x40\xb0\x0d\x58\x87\xad\x2d\xd6\x02\x7b\x28\x32\x80\x91\x4e\x16\x1b\x8b\xd4\x48\x2a\xa9\x1b\xe4\xbf\xef\x8e\xa4\x14\x39\xa2\x62\x26\x76\xdb\xe5\xc5\x12\x75\x3c\xde\x1d\x8f\x77\xdf\x1d\x73\x75\xc0\xf0\x6f\xf2\xd8\x64\x25\x54\x7c\xf2\x9c\x4d\x4a\x6b\xeb\xe7\x8b\xc5\x47\xa3\xe4\xdc\x8f\x1e\x2b\xbd\x5c\xe4\x9a\x17\x76\xfe\xe4\xc7\x85\x1f\x7b\x34\x99\x85\x99\x22\xef\xcd\x5a\x0a\xfb\xb9\xa9\x8f\x33\x55\x05\xba\xc5\xc5\xd3\xc5\x59\x23\x56\xf9\xb1\x06\x53\x2b\x69\xe0\x98\x38\xb7\xb3\x73\x30\x99\x16\xb5\x15\x38\x84\x5c\x4e\x98\xa3\x65\x1d\x6d\x4b\x67\xd7\x35\x10\x81\x3a\xfb\x08\x99\x6d\x47\x79\x9e\x0b\x9a\xca\x57\x6f\xb5\xaa\x41\x5b\x01\x06\xa9\x0a\xbe\x32\x10\x48\x34\xfc\xdb\x08\x0d\x24\xe3\x07\x37\xd2\x8d\x82\xb1\xaf\xf2\xc0\xc9\x0d\x56\x2a\x87\xfe\xbb\xb1\xdc\x36\xe6\xd6\x88\xb6\x90\x9f\xd8\xfe\x60\x21\xa4\x30\xe5\xed\xd1\xbc\xd1\x9c\x44\xfb\x73\x83\x01\xea\xa5\x1a\x9d\xa1\x94\x6e\xec\xb4\xb3\x02\x31\x21\x72\x12\xff\xea\x86\x1e\xb4\x56\x7a\x63\x68\xdc\x1a\xdd\xd7\xed\x56\xd9\xb0\xc3\xc0\x3a\x37\x06\x01\x63\xf8\x12\x26\x1b\x5f\x4e\x6f\xb1\xa8\xfb\x6b\x5c\x8d\x33\x89\x7d\xdc\x50\xc6\x58\x2d\xe4\x72\x32\x20\xba\x9e\x0d\x99\xd6\x25\x37\x77\xb0\xbc\xe5\x55\xef\x4b\x60\x6e\x06\x13\x92\x5d\x96\x22\x2b\x99\xc5\x21\x67\x5c\xa6\xb2\xac\xd1\x68\x82\x19\x13\x05\x13\xb6\x7b\x27\xc2\x15\xce\xd3\x0a\xb7\xcb\xa0\x64\x8c\xb3\x76\xfb\x8e\x27\xb3\x24\x5d\x46\xa8\x40\x36\x15\x59\xdc\xfb\xc3\xea\x02\xbd\x8e\x4d\xc8\x01\xe8\x97\xbc\xce\x0d\xd4\x2b\x2e\xe9\x97\xd7\xf5\x6a\x3d\x61\xa7\x43\xc3\x1c\xc4\xdf\x7a\x06\x1b\x31\xd4\xd7\x72\xa0\xd8\x79\x89\x9d\x90\x9d\x1c\xec\x66\x91\x54\x17\x1b\xd9\x96\x42\xe9\x8a\x13\x97\x49\x8e\x5b\x30\xb7\xa2\x82\x34\x6f\xec\x69\xb2\x55\x04\x21\x2d\x2c\x41\x8f\xc9\x50\xa1\x1b\x54\xce\x3b\x9e\x3c\x68\xbf\x45\x15\x3b\x6b\x5f\x6b\xbf\xfd\xea\x31\x0b\x89\x25\x86\xdb\xdd\xf6\x39\xae\xda\xd8\x99\x7f\x45\xd4\x78\x60\x0b\xd0\x20\x33\x98\x31\x6e\x98\xa9\x21\x13\x85\x80\x3c\xf5\xfc\xa6\xed\xbe\xd7\x2d\x55\xb0\xbf\xfc\x91\xcf\x99\x9f\xc7\x54\xe1\x82\x91\xd3\x8e\x1d\x0a\x6b\x50\xe8\xa5\x40\x01\xd6\x81\x62\xc6\x30\x4a\xd1\xb8\x27\x79\xf5\x0b\x2b\x68\x80\x5e\x0c\xbb\x14\xb6\x54\x0d\x72\x91\x70\xf4\x70\xad\x12\x1c\x0b\x2e\x40\x0e\x95\x1c\xe4\x6f\xc9\x1c\x21\xea\x50\x2b\x3a\x95\xec\x6c\x4d\xb2\xb5\x5a\xb6\x01\x74\x6a\x18\xcf\x5c\xbe\x63\x87\x06\xc0\x4f\x72\xc0\x60\xa0\xc5\xd7\xf2\x5d\x2f\x4f\xcc\x79\x5d\x20\x88\x8d\x93\x60\x3b\xb9\x74\x58\x33\xd5\x75\x5e\xf3\xaa\xb3\xa4\x9f\x8a\x8f\xbc\x67\x6c\x97\xd5\x9c\x29\xf7\xe9\xe0\x4e\xff\x6f\x15\x5b\x03\xfb\xab\xfd\xe4\x5b\xdc\x90\x25\xfa\xa0\xa1\xbc\x7a\xc9\xb5\x74\x93\xd0\xb5\x1a\x5b\x37\x21\xfb\x42\x1d\xcd\xb5\x31\x10\x02\xe8\xc9\x32\x21\xed\xe0\xf2\x67\x18\xf2\x93\x98\x4a\x5e\xed\x19\x2b\x5d\xf0\x55\xe3\x58\xc6\x3e\x06\x8c\xbb\xd7\x05\x77\x47\x7c\x09\xf1\x08\x73\xbe\x28\xf0\x10\x6c\x0f\x49\xac\x20\x1c\x57\x72\x99\x53\x38\xe2\xd9\xf9\xd6\x98\x34\x63\xf0\xc9\x6a\x7c\xc1\x09\x98\xaf\x95\x23\x9b\x1e\xb7\x4b\x9a\xc5\x8b\x76\xce\xcb\xc5\x0b\x3f\xe7\xe5\x14\xa3\xb5\xc6\xf8\xa4\x30\x6e\x07\xc6\x6d\x29\xe3\x4a\x0d\xe4\x7e\xa9\xf4\xb9\xa9\xf9\x10\x3e\x7e\xfb\x10\x57\x73\x5b\xc6\xc6\x8d\xf8\x1c\x0d\x7d\xa6\xe4\xdf\xfd\xf0\xec\x7f\x11\xfc\xfa\x1b\xeb\xbe\x85\x5d\xda\x6b\x08\x74\xf6\x49\x95\xf4\x2d\x12\x77\x92\x06\x69\x66\xe8\x07\x2b\x04\x89\x17\xc0\x82\x3f\x79\x1d\xc8\xe9\x5a\xb7\xea\x79\xd0\xe1\x34\x54\xd2\x8b\xee\xeb\x94\x8a\x17\x82\x01\x99\x92\x96\x0b\x09\xfa\x68\xaf\x2a\xba\xad\x4e\x55\xf1\x1d\x12\x0f\x55\x44\x01\xcf\xd6\x16\xcc\x56\xb9\x76\x01\xc1\xe3\xde\x98\x2c\xfc\x6f\x27\x73\xa4\x67\x59\x09\xd9\xb9\x69\xaa\xa1\x22\x25\x7c\x9a\x23\x6a\x54\x79\x3a\x54\x1c\xa1\x42\xc7\xb1\xa0\xdd\xb2\xff\x7c\xe0\xf3\xe2\xc9\xfc\xa7\xd3\xab\x67\xdf\x5f\x3f\x7e\x58\xd4\x6b\xe3\xce\x37\x43\xf8\x6e\x99\xf1\x3c\xb2\x53\x40\xb8\x57\xaa\x4f\x73\x8c\x2d\xd9\x2d\x02\xcd\xc9\xba\x0c\xf1\x40\xa6\x2a\x78\xce\xa6\x54\x7c\x63\xc1\x30\xa5\xe6\x40\x86\xa1\xc6\x61\x6e\x2c\x29\x58\xf8\x30\x63\xd3\xa6\x9e\x5b\x35\x27\x58\xe3\xa8\xa4\xda\x24\x94\x40\xfd\x03\xae\xd7\x48\x5a\x83\xcc\x51\xf8\x21\x37\xaa\xf5\x25\x45\x31\x84\xf2\x52\xd9\x1e\xf7\x82\x63\xf6\xf2\xeb\xf7\x5a\x11\xfd\xbc\xc5\x3c\x09\x56\x38\x32\x67\x53\x73\x2e\xea\x3a\x4c\xd8\xa0\xba\xc4\x02\x88\x58\x07\x2e\xb8\xd8\x21\x2c\x8f\xd9\x19\x64\xbc\x31\xd0\x6b\x6e\x50\x13\x24\x07\x12\xd5\x60\x9a\x0c\xdc\x8f\xf6\xd6\xf2\x08\xaa\x11\xd8\xba\xb1\x9c\xeb\x77\x78\xe3\xd0\xa3\x5f\x93\x9e\x32\x8e\xc5\xdb\x2a\xbc\x04\xdd\x92\xf1\x19\xf5\x3e\xd2\x77\xff\xbd\xa8\xc8\xb4\x18\x0c\x80\x67\x65\x68\x18\x6d\x9a\x90\x2a\x1b\x5b\x6a\xd5\x2c\xcb\xad\xf6\x88\x1e\xc4\x07\x1c\xc8\xd4\xe3\xb3\x11\x23\xa8\xa7\x84\x44\x6c\xf2\x18\x2b\x60\x92\xe6\xd1\xa2\xd7\x63\x5c\xf8\xae\x50\xcc\x68\x37\xd5\x36\xb5\xa3\x76\x63\xe1\x3b\x59\xbb\xf1\x70\x4d\xb0\xdd\x58\xf8\xfe\x59\x02\x8f\x28\x8b\xeb\x24\x57\xf3\xd5\xf8\xfd\x9a\x13\x86\x35\xc6\x17\xc8\xce\xdf\xee\x40\xa3\xe7\xb0\xf6\x84\x01\xf7\x50\x8d\xf0\x65\xfc\x6f\xd4\x46\xbe\xf9\x92\x68\x8d\x10\xda\x92\xcd\xf1\x73\x08\x85\x0e\xcf\x6d\xc4\x43\xea\x74\xf4\xad\xb2\x55\x6b\xae\x35\x5f\x8f\x11\x09\x0b\xd5\x96\xc3\xd3\x6a\x7f\xd7\x75\x86\x57\xcf\x5f\x63\xec\xe0\x33\xe7\x52\x5d\xca\x93\x02\xe1\xc1\x49\xeb\xa1\x49\xd6\xfa\xfd\xdd\x9b\xd7\xac\x56\x84\xa1\xb4\x21\xb7\x41\x40\x58\x88\x65\xe8\x41\x32\x57\xee\x99\xb6\x31\x10\xda\x5f\xcc\x57\xb8\x14\xd0\x57\x6b\xe6\x56\xc6\x47\x1c\x0f\x41\xb7\xcb\x46\xc8\x4e\xa1\xc1\x75\x67\x71\xc2\xa7\x5d\xb6\x63\x87\xb4\x31\x8c\x2e\x4a\x1c\xa7\xa3\x3d\xed\xc7\x20\x8b\x24\x9a\x30\x68\x95\x6c\xba\x37\xc1\x0a\xf0\xa9\x56\xe1\xec\xdd\xcb\xbb\x1e\x74\xa6\xac\x6e\x20\x49\x1b\xd7\xb9\x49\x57\xe6\x57\x47\xbe\xd1\x69\x1b\x69\xb1\xf9\x9b\x0c\xdd\x48\xea\x77\x38\x98\xae\x74\x0e\x7a\x6f\x9b\x17\x0f\x19\xbe\x63\x98\xb8\x93\x5d\x99\x93\xac\xfe\x49\x57\x36\xdd\x2a\xed\xe3\x46\xf8\xc2\xca\x76\xed\x88\x44\x7d\xe3\x17\x7b\x83\x30\x74\xcb\xa2\x6e\x52\x7a\xe1\x70\xd0\x5b\x7d\x0c\x3e\xf4\xae\x42\x47\x6b\x8a\x28\xb4\xa3\x2a\xed\x0f\x90\x4b\x57\x18\x3f\x8d\x56\x2b\xee\x42\x75\x5b\x7f\xe6\xef\x12\x5c\xc0\x19\x74\x4c\x1c\x62\xed\x80\x30\xdd\xd4\x51\xec\x6a\x33\xc3\x61\x0f\x67\x07\xa2\xf1\xd6\x71\x5c\x81\x4d\x4c\xba\xee\xee\xdc\x7a\xf0\xf2\x7a\x78\x1d\x7c\x4f\x2b\xdd\x2c\x62\x9a\x2c\x03\xc8\x3d\x94\x8d\x21\xdc\xf1\x75\x47\xee\xb7\xb6\x2c\x7d\x67\xcf\xf5\x3a\x7e\x83\xfd\xa5\x96\xb8\xe3\x8a\x6c\x4b\x57\x20\xda\x0d\xb8\x8e\xde\xa9\x6f\x73\x35\xac\xef\x9a\x95\xed\x10\xfe\x4d\xc1\x23\x87\xde\xd7\x43\x5d\x1d\x5d\x0c\x77\x3d\xac\xf0\x8e\x54\xbf\xf1\x23\xdf\x15\xfc\x29\x57\x34\xf1\x7f\x16\x48\x8a\x25\x21\x56\x1c\x5c\x1f\xfc\x07\xe7\xcb\x1b\x98\x11\x22\x00\x00")
 
 func schemaBuildResponseJsonBytes() ([]byte, error) {
 	return bindataRead(
@@ -129,8 +178,48 @@ func schemaBuildResponseJson() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "schema/build.response.json", size: 264, mode: os.FileMode(420), modTime: time.Unix(1541191410, 0)}
-	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x94, 0x42, 0xe1, 0x9f, 0x17, 0xf9, 0x93, 0xec, 0x22, 0x35, 0x64, 0xfc, 0x44, 0x9e, 0xad, 0xa7, 0x3b, 0xca, 0xd0, 0x32, 0xa6, 0xd1, 0x97, 0xef, 0x50, 0xa7, 0x85, 0x7f, 0xc5, 0x23, 0xec, 0xac}}
+	info := bindataFileInfo{name: "schema/build.response.json", size: 8721, mode: os.FileMode(420), modTime: time.Unix(1792198820, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xad, 0xf1, 0x4, 0xe, 0xae, 0x30, 0x99, 0xd9, 0xec, 0x5e, 0x84, 0x29, 0x2a, 0x4f, 0x79, 0xe9, 0xa5, 0xaa, 0x1d, 0x81, 0x58, 0xc0, 0x73, 0x6b, 0xf8, 0xea, 0x53, 0xb2, 0x5b, 0x62, 0xa1, 0x8a}}
+	return a, nil
+}
+
+var _schemaChangeJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x92\xc1\x4e\xe4\x30\x0c\x86\xef\xf3\x14\x56\x17\xa9\x20\x95\x16\x2e\x8b\x98\xdb\x8a\xd5\x1e\x38\x00\x62\xb9\xad\x38\x84\xc4\x6d\x83\xda\x24\x24\xee\xc0\x2c\xe2\xdd\x49\xd2\xcc\xd0\x8e\x06\x4d\x0f\xad\xe2\xf8\xff\x6d\x7f\xee\xfb\x02\xfc\x93\x1d\x39\xde\x62\xcf\xb2\x25\x64\x2d\x91\x59\x56\xd5\xb3\xd3\xea\x74\x8c\x96\xda\x36\x95\xb0\xac\xa6\xd3\xb3\x8b\x6a\x8c\xfd\xc8\x8a\xa4\x94\x62\xa2\x6a\x24\xfd\x1f\x4c\xc9\x75\x9f\xf2\xaa\xd5\x79\xc5\x5b\xa6\x1a\x2c\x83\xe3\x46\x25\xd0\x71\x2b\x0d\x49\x1f\xf2\xea\x5f\xe0\xa4\x6a\x3a\x04\x21\xeb\x1a\x2d\x2a\x8e\xf0\x84\xf4\x8a\xa8\x80\x81\x45\xa7\x07\xcb\x31\x77\xc0\x07\xeb\x6f\x09\x1c\x31\x42\x60\x4a\x80\x24\x07\xde\x4d\x5a\x14\xc0\xb5\xaa\x65\x33\x58\x16\x7c\xcb\x4d\x2d\x5a\x1b\x0c\x45\xf4\xd3\x33\x72\xda\x44\x99\x10\x32\xa4\xb1\xee\xce\x6a\x83\x96\x24\x3a\x9f\x55\xb3\xce\x61\x4a\xb1\xf8\x32\x04\x5f\x1f\xfe\x17\x23\x31\xaa\x4d\xb2\x88\x27\xc3\xa8\xcd\xe2\xf1\x31\xa9\xcc\xd4\xee\x7d\xa6\x9b\x9e\xf7\x61\x78\xf0\x9d\x82\xae\x61\x04\xb6\x84\x9c\x5b\xf4\x73\xe6\xf0\xda\x7a\x10\xd4\xe2\x16\x05\x08\x8d\x0e\x94\x26\xc0\x37\xe9\xa8\x80\xdc\xcf\x93\xf2\x18\xa4\x16\xd6\x20\x1d\xf4\xd2\x05\xb6\x50\x5b\xdd\x47\x8b\x19\xc2\x22\x32\xcc\x07\x23\xbe\xea\x7c\xe9\x27\xc0\x57\xac\x1b\x36\xeb\x71\xa3\xd9\x94\x7c\xbc\x2e\x27\x60\x66\xe4\x1d\x59\xdf\xc2\xee\x2d\xaa\xa1\x0f\x68\x21\x1b\xc7\xcc\x8a\xb8\x95\xf0\x19\xfb\xc9\xe0\x71\xab\xf8\xd8\x65\x7e\x88\xe5\xf5\xdf\xdb\x1b\x30\x5a\x2a\x42\x0b\xc7\xf7\x7f\xae\xe0\xe7\xe5\xd9\xf9\x49\xc0\x1b\x29\x44\xc4\x62\x3b\x6a\xe1\xd1\x76\xfe\xbf\x59\x21\x90\x9e\xa3\xde\xfb\x57\x7d\x37\xe3\xde\x86\x13\xc4\x83\x3d\x5f\xcd\x60\xa7\x4e\xb7\xcb\x3c\x96\xb5\xdf\xd6\xfa\xa4\xdc\x5f\x24\xad\xe2\x60\x91\xdf\xd3\x95\xed\x16\x99\x7a\x2f\xc6\xf7\xc7\xe2\x13\xa6\x35\xca\x56\x24\x04\x00\x00")
+
+func schemaChangeJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_schemaChangeJson,
+		"schema/change.json",
+	)
+}
+
+func schemaChangeJson() (*asset, error) {
+	bytes, err := schemaChangeJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "schema/change.json", size: 1060, mode: os.FileMode(420), modTime: time.Unix(1792195963, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x1d, 0x6c, 0x82, 0x4f, 0x14, 0x14, 0x8d, 0x5d, 0xd4, 0x56, 0x93, 0x68, 0xf7, 0x5, 0xe2, 0xa1, 0xc5, 0x2, 0xe1, 0x36, 0xd4, 0xbb, 0xed, 0x21, 0x99, 0x64, 0xfa, 0x9e, 0x45, 0x7a, 0x11, 0xc4}}
+	return a, nil
+}
+
+var _schemaEventJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb5\x55\x4d\x6f\xdb\x30\x0c\xbd\xe7\x57\x10\xde\x80\xac\x80\x13\xa7\xc0\x80\x01\xbe\x15\xe8\x65\xc0\x80\x0d\xd8\xb1\xe9\x81\xb1\x19\x5b\xad\x2d\x69\x12\x9d\xb4\x0b\xf2\xdf\x47\xf9\xa3\x4d\xba\xa4\x28\xb6\xda\x17\x8b\x14\xf9\xf4\xc8\x67\xca\xbb\x09\xc8\x13\x7d\xf4\x59\x49\x35\x46\x29\x44\x25\xb3\x4d\x93\xe4\xce\x1b\x3d\xeb\xbc\x73\xe3\x8a\x24\x77\xb8\xe6\xd9\xe2\x4b\xd2\xf9\x3e\x44\x71\x9f\xa9\xf2\x83\xac\x42\xf1\xef\xc6\xce\x33\x53\xf7\x71\xc9\xe6\x32\xa1\x0d\x69\x9e\x07\xc0\x21\x29\x27\x9f\x39\x65\x59\x89\x4b\x92\xaf\x34\xb4\x31\xe0\xc8\x1a\xc7\x94\xc3\xea\x11\x50\x03\x66\x21\x02\xb6\xa5\xaa\x08\x94\x6c\x37\xda\xc7\xb0\x75\x8a\x99\x34\xb0\x11\x9f\x07\xcf\xb9\x69\x18\xd0\x03\x82\x57\xba\x90\xd0\x4a\x69\x02\xeb\x68\xad\x1e\x04\x6b\xab\xb8\x84\x69\x9a\x76\xdc\xd2\x74\x0a\x9f\xa8\x98\x1f\x7a\x60\xb7\x8c\xf8\xd1\xd2\x52\xc8\x2c\x23\xeb\x4c\xe1\xc8\xfb\x65\x14\x07\x8b\x5c\x26\xd4\xc2\xd6\xe7\xc5\x7e\x7a\x31\x1f\x6a\x08\x09\x81\xbc\x59\xdd\x51\xc6\x83\xd7\x68\xfa\xbe\x16\xf7\x4d\x6b\x86\x67\xf7\xb4\x3a\x55\xfa\x8f\xfe\x30\x30\x6b\xe0\x92\xfa\x92\x87\x43\x9e\xb2\x30\xcf\x55\xd8\xc0\x4a\x12\x84\x12\x2b\xf2\x92\xbe\xc6\xca\xd3\x8b\x50\x47\xbf\x1a\xe5\x28\xa8\x72\xd3\xb3\x8c\x61\x28\x23\x82\xdb\x17\xe1\xf6\x10\xef\x98\xeb\x61\x99\xbb\xe7\x82\x3d\x3b\x69\x73\x00\x25\xdd\xd4\xdd\x31\x43\xcf\x04\x1f\xf6\xf1\xdf\x28\xc3\xf1\x47\x40\x92\xbd\x22\x17\x80\x6a\xa5\x55\xdd\x62\x2d\x82\x85\x0f\xbd\x75\xb9\x58\x9c\x84\xab\xe5\x28\x2c\x4e\xf3\x82\xfd\x51\xfc\xb3\x75\x00\xf4\xba\x26\x57\xb0\x45\xa7\x05\x4b\x14\x41\x86\xdc\x90\x07\x6d\x58\xba\xad\xaa\x51\x45\x1a\xca\x1a\x49\xa4\xbe\xaa\x73\x1a\xbd\xd6\xd4\x4e\xa3\x6f\xa4\x0b\x2e\x83\x2e\xef\xd0\x64\x0d\x4a\x33\xb9\x9a\x72\x85\x4c\x20\x33\x6c\x65\x8c\x8f\xc6\xa0\x1b\x54\xb9\x08\xbe\x5e\x87\x0d\x84\xcc\x11\x86\xeb\xc1\x37\xab\x99\x7c\x6e\xa6\x91\xaf\xea\xe2\xfd\x75\xd0\x58\xb7\xef\x0d\x56\xcd\x68\x6a\x74\x05\x9f\x13\xa3\xa5\xf0\x36\x25\x4e\x64\x77\xc4\x25\xfd\xbf\x65\xfa\xc9\xc8\x8d\x87\xac\x44\x5d\x50\x27\x42\x68\xbe\x67\xb2\x63\x5f\x59\x83\x0a\xbe\xa5\x30\x96\x0c\xa1\x92\x71\x44\xe8\x79\xbf\x81\x01\x86\x7f\x5e\x5b\x6a\x93\x65\x44\x79\x67\x84\xeb\xa6\x77\xdf\x2b\x6b\x65\xf9\x0f\x83\x7b\x7e\x50\xdb\xd5\xed\x64\x3f\xf9\x03\xfe\x5d\x35\x97\x03\x08\x00\x00")
+
+func schemaEventJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_schemaEventJson,
+		"schema/event.json",
+	)
+}
+
+func schemaEventJson() (*asset, error) {
+	bytes, err := schemaEventJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "schema/event.json", size: 2051, mode: os.FileMode(420), modTime: time.Unix(1792197589, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x35, 0xa, 0x8b, 0xfc, 0x7, 0xc4, 0xc, 0x34, 0x46, 0x36, 0x4c, 0x56, 0xf5, 0xe0, 0xdc, 0x4e, 0xb, 0x4c, 0x7d, 0x93, 0x65, 0x86, 0x5, 0x51, 0x31, 0xa1, 0x8e, 0x6b, 0x19, 0xff, 0x6a, 0xa3}}
 	return a, nil
 }
 
@@ -154,7 +243,7 @@ func schemaInitRequestJson() (*asset, error) {
 	return a, nil
 }
 
-var _schemaInitResponseJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x91\xb1\x4e\x03\x31\x0c\x86\xf7\x3e\x45\x14\x90\xba\xb4\x17\x98\x90\x6e\xe3\x0d\x10\x8c\x88\x21\x24\xbe\xab\xab\x6b\x1c\x1c\x1f\x12\x54\x7d\x77\x94\xe4\xae\xbd\x81\x0a\x96\x0c\x5f\xfc\xfd\xb6\xec\xe3\x4a\x29\xa5\xf4\x6d\x72\x3b\x38\x58\xdd\x2a\xbd\x13\x89\xad\x31\xfb\x44\x61\x5b\x69\x43\xdc\x1b\xcf\xb6\x93\xed\xdd\x83\xa9\xec\x46\x6f\x26\x13\xfd\xc2\xea\x51\xbe\xc7\xd8\x38\x3a\x4c\x75\xe6\xf3\xde\x60\x40\x69\x18\x52\xa4\x90\xa0\xc9\xc1\xb3\xec\x21\x39\xc6\x28\x48\x21\x87\x3c\x43\xa2\x91\x1d\xa8\xc8\x24\xe4\x68\x50\xeb\xec\xae\xd5\x59\x9e\x45\xf9\x8a\x90\x0d\x7a\xdf\x83\x93\x99\x5a\xef\x31\x67\xd9\xe1\x89\x29\x02\x0b\x42\xd2\xad\xea\xec\x90\x60\x2a\x61\xf8\x18\x91\x21\xcf\xfc\x5a\x48\xa1\x8e\x42\x87\xfd\x4b\xdd\xc1\xe6\xc2\x93\x58\x81\x47\x57\xe6\x2b\xf4\x6d\x8a\x89\xcb\xfc\xe3\x95\xa0\xe5\x4f\xdd\x15\x43\xf7\xff\x15\xeb\xb3\x7c\xba\x32\xd2\x5f\x0d\x7e\xbd\x86\x2d\x6e\x3d\xc3\xa5\xc3\xaa\xbe\xa7\xd5\x4f\x00\x00\x00\xff\xff\xfa\x3b\x73\x31\x11\x02\x00\x00")
+var _schemaInitResponseJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb5\x53\x3d\x6f\xdb\x30\x10\xdd\xfd\x2b\x0e\x6a\x01\x2f\x8e\xd4\x4e\x05\xb2\x65\xec\x50\xb4\x48\xc7\xa6\x03\x4d\x9e\x24\x06\x32\xc9\x1e\x8f\x29\xd4\x20\xff\xbd\xfc\x92\xad\x06\x36\x12\x14\xad\x07\x43\x7a\x7c\xef\xdd\xdd\x3b\xf1\x71\x03\xf1\xd7\xbc\xf5\x72\xc4\x83\x68\xae\xa1\x19\x99\xdd\x75\xd7\xdd\x7b\x6b\xae\x0a\xda\x5a\x1a\x3a\x45\xa2\xe7\xab\x77\x1f\xba\x82\xbd\x69\x76\x55\xa9\xd5\x4a\x35\x68\xfe\x15\x5c\x2b\xed\xa1\xf2\xba\x87\xf7\x9d\x36\x9a\x5b\x42\xef\xac\xf1\xd8\x26\xe3\x45\xac\xd0\x4b\xd2\x8e\x75\x84\xa2\xc9\x2d\x7a\x1b\x48\x22\x38\xb2\x6c\xa5\x9d\x60\x9b\xb4\x5b\x38\x8a\x17\x21\xcf\x0e\x93\xc2\xee\xef\x51\xf2\x82\x0a\xa5\x74\xf2\x12\xd3\x17\xb2\x0e\x89\x35\xfa\xc8\xea\xc5\xe4\xb1\x52\x08\x7f\x04\x4d\x98\x7a\xfe\x96\x91\x8c\x4a\x6b\x7a\x3d\x7c\x2d\x19\xec\x4e\xb8\x67\xc1\x78\x23\x73\x7f\x19\xfd\x5e\x6d\xdc\xda\xff\xf1\x82\xd1\xfa\xa4\x64\x45\xd8\xbf\x3e\xe2\xe6\x28\x7e\xba\xd0\xd2\x4b\x05\xce\x6e\x43\x64\x6d\x59\xc3\xd9\x0a\x6e\x12\xe6\x52\x81\x67\x0b\xfb\xec\x4a\xdc\x50\x4c\xa1\xb7\x04\xb1\x9a\x0b\xac\xcd\x00\x3c\x22\xc8\x51\x98\x01\x3d\x2c\xb9\x03\xdb\xf8\x2c\xe4\x98\x4f\xa3\x5d\x06\xf3\x50\x2d\x7c\xec\xe1\xa0\xbd\x8f\xda\x5d\x3e\x16\x03\x1a\xae\x86\xd1\x63\xed\xb7\x9f\x33\x2e\xe8\x58\x28\x10\x25\x76\xb6\x82\x9f\x9a\xff\xac\x50\x16\x13\x48\xe4\xe1\x57\x3b\xfe\x77\xb9\x09\xe7\xa6\xf9\x95\xc1\xdd\x9c\xf2\xca\xb2\x34\xc5\x32\x5a\x4c\x28\x75\x4e\xf5\x32\xb4\x70\xbb\x64\x97\xe8\x0b\x9c\xe2\x10\x0c\x06\x4b\xa6\xfb\x98\x40\xcc\x95\xe3\x5b\x24\x05\xa7\xd2\xe3\xff\x99\x73\xd2\x07\xcd\xfe\xc5\x11\x8f\x97\xb9\xf0\x4f\x5f\x40\x5c\xdd\x7a\xbe\xad\xaf\x5f\x8f\xdf\x81\x7d\x40\x22\xad\x96\x9d\xe6\xfd\xc7\x73\x85\xbd\x08\x13\x57\xa7\x16\x3e\x89\x39\x0d\x5c\xd9\x0a\xcd\x19\xcf\xbb\xda\xe7\x5d\x03\xf5\xb2\xce\xa0\x4d\x66\xed\x83\x9e\x54\xee\x07\x3d\xff\x55\x46\xb5\x91\x67\x19\x6d\xca\xff\xd3\xe6\x37\x3f\x44\x36\xe3\x55\x05\x00\x00")
 
 func schemaInitResponseJsonBytes() ([]byte, error) {
 	return bindataRead(
@@ -169,12 +258,72 @@ func schemaInitResponseJson() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "schema/init.response.json", size: 529, mode: os.FileMode(420), modTime: time.Unix(1541191728, 0)}
-	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x3, 0xd5, 0x7c, 0x81, 0x84, 0xea, 0x19, 0x72, 0x44, 0x93, 0xf4, 0x53, 0x2d, 0x49, 0x6e, 0xa4, 0x1b, 0x5c, 0xa3, 0x27, 0x9d, 0xe0, 0x41, 0xd0, 0x8d, 0xe5, 0x72, 0xce, 0x74, 0xc9, 0xc9, 0x2b}}
+	info := bindataFileInfo{name: "schema/init.response.json", size: 1365, mode: os.FileMode(420), modTime: time.Unix(1792197131, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x8f, 0xdc, 0xb0, 0xf0, 0x28, 0x39, 0xba, 0x90, 0xc6, 0xc, 0x6a, 0xf6, 0xdb, 0x63, 0xbc, 0x38, 0xbc, 0xd6, 0x18, 0xa, 0xc1, 0x6f, 0xb5, 0x9d, 0x46, 0xb3, 0xdb, 0x8f, 0x5, 0x4d, 0xe6, 0x4e}}
 	return a, nil
 }
 
-var _schemaResourceJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x91\xcf\x4e\xf3\x30\x10\xc4\xef\x7d\x8a\x95\xbf\xef\x00\x12\x4d\x40\x1c\x90\x72\x43\xe2\xc8\x01\x21\x6e\x88\x83\x71\x36\xce\x96\xc6\x36\xeb\x35\x02\xaa\xbe\x3b\x72\xf3\xa7\x69\x01\x89\x4b\xa4\xcc\xfe\x76\x33\x93\xd9\x2c\x00\x00\xd4\xff\x68\x5a\xec\xb4\xaa\x40\xb5\x22\xa1\x2a\xcb\x55\xf4\x6e\xd9\xab\x85\x67\x5b\xd6\xac\x1b\x59\x9e\x5f\x95\xbd\xf6\x4f\x9d\x0d\x9b\x54\xcf\xb6\x2c\xc9\x67\x0a\x85\xf1\xdd\xc0\x95\x6f\x17\x25\x63\xf4\x89\x0d\x16\xf9\xe6\xb8\x57\x63\x34\x4c\x41\xc8\xbb\xbc\x7f\x0d\x23\x05\x31\xa0\xa1\x86\x8c\xce\xb3\x62\xe4\xe5\x23\x60\x06\xfd\xf3\x0a\x8d\x8c\xaa\xae\x6b\xca\x98\x5e\xdf\xb1\x0f\xc8\x42\x18\x55\x05\x8d\x5e\x47\x1c\x10\xc6\xd7\x44\x8c\xd9\xe5\xe3\x4e\xd9\x9f\xdb\xbd\x3e\x0d\x5c\x98\x1f\xd8\x1c\x91\x73\xe5\x27\xfb\xf7\xa3\xf9\x4c\x17\xf0\xd0\x52\x04\x8a\xa0\xe1\xc6\x9b\x17\x64\xa0\x4e\x5b\x04\xc6\x06\x19\x9d\x41\x38\x21\x67\xd6\xa9\x26\x67\x41\x5a\x04\xd1\xf6\x74\x4c\x7a\xfc\x61\x15\x85\xc9\xd9\xe3\x69\x47\xee\x16\x9d\x95\x56\x55\x70\x39\x8d\xb6\x7b\x4a\x19\xef\x1a\xb2\x7f\xb7\xde\xf3\x89\xfb\xff\x3e\x65\x88\xe8\x04\xc4\xef\x7c\x4e\x1d\x1d\xc4\xf2\x0e\xf0\x1d\x4d\x9a\xf7\xf5\x2d\xc5\x41\x6f\xd3\xf4\x97\xfe\x84\x13\xee\x33\x2d\xfa\xe7\x76\xf1\x15\x00\x00\xff\xff\x02\x4c\x42\xf2\xad\x02\x00\x00")
+var _schemaLimitsJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x52\x4d\x6f\xdb\x30\x0c\xbd\xe7\x57\x10\x6a\x0f\x29\x96\xd8\xc9\x80\x60\x68\x2f\x05\xb6\xc3\x0e\x5b\x86\x62\xd8\x4e\x71\x06\xc8\x32\xed\xb0\xb3\x3e\x20\xd1\x5d\xbc\xa2\xff\x7d\x72\x1c\xbb\x5d\xd0\xa0\x3e\x18\xc2\xe3\x23\xf9\x1e\xc9\xc7\x09\xc4\x4f\x5c\x06\xb5\x43\x2d\xc5\x0d\x88\x1d\xb3\xbb\x49\xd3\xfb\x60\xcd\xbc\x47\x13\xeb\xab\xb4\xf0\xb2\xe4\xf9\xe2\x43\xda\x63\x17\x62\x76\xcc\xa4\xe2\x45\x56\x45\xfc\xb7\x71\x89\xb2\xfa\xc8\x4b\x1f\x96\x69\x4d\x9a\x38\x24\x5d\xc5\x21\xab\xc0\xa0\x3c\x39\xa6\x08\xc5\xec\xef\x18\x6c\xe3\x15\x42\x4f\x05\x5b\x82\x54\x5d\x10\x94\x35\x2c\xc9\xa0\x0f\x09\x7c\xed\x83\xd2\x23\x28\xe9\x1c\x16\x90\xb7\xc0\x3b\x04\x2d\xf7\xa4\x1b\x3d\x64\xa3\x29\x6d\x2c\x36\x86\x65\x85\x86\x93\xa1\x35\xb7\x0e\xbb\x9e\x36\xbf\x47\xc5\x03\x2a\x8b\x82\xba\x86\xb2\xbe\xf3\xd6\xa1\x67\xc2\x10\x59\xa5\xac\x03\x1e\x29\xee\x65\xe0\xf1\x80\x1d\x70\xe5\x9a\xff\x91\xd7\x1c\x7e\x6b\x74\x8e\xbe\x33\xf6\xe9\xee\x67\x80\xa9\x96\x2d\xe4\x08\xa5\xef\x7d\xca\x7a\x06\x58\x25\xb0\x48\x56\x57\x83\xd2\xb1\xd4\xa0\xd8\x1c\x6a\x9c\x46\x71\xaf\xea\x26\xd0\x03\xae\xc9\x74\x53\x88\xcc\xc5\xc8\x78\x7a\x26\x0b\x8d\xda\xfa\xf6\x4d\xa5\xeb\x03\x2d\xca\xa1\x38\x3b\x0f\x64\xe2\x18\x19\xe3\x4e\x3c\xfc\x89\x10\x48\xc8\xc9\x48\xdf\x42\x63\x88\x21\x34\x65\x49\x7b\x98\x76\xe2\x33\xb1\x5a\xbe\xd7\x99\x98\xc5\xd7\x32\x59\x55\x99\x38\xeb\x65\x03\x82\x0c\x63\xd5\xd9\x01\x11\xd8\x93\xa9\x04\x6c\x4f\xc8\x7a\x74\xb4\x3c\x89\x38\xc9\x8c\xfe\xa0\xf7\xd7\x66\x31\xbf\xde\xbe\x9b\x66\x59\xd2\xbf\xae\x6e\x37\xf9\x6f\x5d\xf1\xc7\x2f\xeb\xcf\x3f\xb6\xb7\x97\xe2\xd5\x69\x38\x2a\xde\xde\xda\xfa\x78\x58\x66\xdc\x5e\x3c\x02\x85\x21\xc4\x79\x4c\xa5\x29\xe2\x79\x79\x94\x45\x38\xbf\xb3\xd1\xe5\x59\x67\xcf\xea\x26\xfd\xff\x69\xf2\x0f\x14\x5f\x7b\x5b\x95\x03\x00\x00")
+
+func schemaLimitsJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_schemaLimitsJson,
+		"schema/limits.json",
+	)
+}
+
+func schemaLimitsJson() (*asset, error) {
+	bytes, err := schemaLimitsJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "schema/limits.json", size: 917, mode: os.FileMode(420), modTime: time.Unix(1792197131, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x61, 0x5d, 0x4a, 0x2, 0xbc, 0xb6, 0xef, 0x9e, 0xe1, 0x97, 0x5e, 0xe0, 0x77, 0xd1, 0x90, 0x70, 0xea, 0xc9, 0xd3, 0x8e, 0xba, 0x3a, 0x35, 0xa1, 0x3f, 0xe, 0xbf, 0xcd, 0xa4, 0x1a, 0xf1, 0xfa}}
+	return a, nil
+}
+
+var _schemaPlanRequestJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa5\x53\x3d\x4f\xc3\x30\x10\xdd\xfb\x2b\xac\x80\xd4\x81\xa6\x81\x09\xd1\x0d\x31\x21\x31\x20\x46\xaa\x0e\x6e\x7c\x4d\x5d\xa5\x76\x38\x9f\x2b\xb5\xa8\xff\x1d\x3b\x5f\xcd\x27\x45\x22\x43\x14\xbf\xbb\xf7\x7c\xef\x72\xf7\x3d\x61\xee\x09\x6e\x4d\xbc\x85\x3d\x0f\x16\x2c\xd8\x12\x65\x8b\x28\xda\x19\xad\xc2\x02\x9d\x6b\x4c\x22\x81\x7c\x43\xe1\xfd\x63\x54\x60\x37\xc1\xac\x64\x4a\xd1\x60\x25\x92\x4e\x36\x9b\xc7\x7a\x5f\xe6\x45\x87\x87\x28\x4b\xb9\x9a\x23\x7c\x59\x30\x34\xf7\xba\x15\x57\x80\x89\x51\x66\x24\x1d\xe4\x34\x3e\xc0\x68\x8b\x31\xb0\x0c\x35\xe9\x58\xa7\x6c\xea\xa9\x53\x56\x71\x2b\x1e\x1d\x33\xf0\x04\xbd\xde\x41\x4c\x15\xca\x85\x90\x5e\x8a\xa7\xef\xa8\x33\x40\x92\x60\x5c\xd6\x86\xa7\x06\xca\x14\x2f\x24\x11\x7c\xc5\xcb\x1c\xa9\x51\x27\xff\x2a\x82\x99\x3f\x14\x45\xf8\x6f\x43\x9c\x20\xc8\x13\x57\xa5\x42\xd6\x94\xfe\x1e\xd2\x68\xc2\xad\x6a\x0d\xa1\x54\x49\x59\x6d\x1d\xdd\x4b\xf5\x06\x2a\xa1\xad\x4b\x79\xa8\x43\xe7\x59\x53\xba\xac\x68\x54\xb9\xd5\x87\x3a\x7a\xbd\x1f\xad\xea\x7b\x7d\xa9\xa3\x8a\xef\xa1\x23\x7e\xb9\xbe\x05\xaf\x3a\xba\x23\xdd\x6a\x2b\x0f\x45\xae\x37\xee\x72\x07\x27\x02\xcc\x07\x68\xc9\xc3\xd3\x6a\x19\xba\xf7\x73\xf8\x79\x1f\x3e\xad\xee\x82\x1e\xe7\x3c\x66\xe4\x9f\x65\x0c\xff\xc7\xdf\x6e\x8d\xb5\xda\xc8\x64\xfc\xde\xb1\xed\x28\x78\x16\xb9\x8f\xcc\x18\x37\x7e\x5f\x0e\x52\x80\x60\x52\x31\xda\x02\x5b\x5b\x99\x8a\xee\xd6\x8c\xfa\x1a\x9c\x9e\x6b\x53\x44\x68\xa1\xef\x72\x32\x7c\x6a\x4e\x73\xb1\x53\xbd\x51\xee\x98\x7d\xb1\x88\xa0\x88\xe5\xd9\x4c\x6f\x72\x5b\xd5\x22\xe4\x9e\x11\xc8\xa2\x72\x9e\xd7\xc7\x3c\x38\xcd\x53\xa7\x8c\xc7\x5e\xa3\x6b\xba\x32\xbb\xbc\xd8\x75\xd3\x67\xd3\x34\xe8\xcd\xec\x5f\x0c\x17\xd6\xce\x93\xf3\xe4\x07\xf4\x0a\xde\x2b\x40\x05\x00\x00")
+
+func schemaPlanRequestJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_schemaPlanRequestJson,
+		"schema/plan.request.json",
+	)
+}
+
+func schemaPlanRequestJson() (*asset, error) {
+	bytes, err := schemaPlanRequestJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "schema/plan.request.json", size: 1344, mode: os.FileMode(420), modTime: time.Unix(1792195963, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x8a, 0xe0, 0xa0, 0x5d, 0x58, 0x93, 0x4e, 0xe2, 0x7a, 0xac, 0xab, 0xf, 0xc9, 0xfc, 0x7e, 0xcb, 0x90, 0x72, 0x21, 0xe0, 0x19, 0x6, 0x91, 0x50, 0x7, 0xee, 0x9a, 0xda, 0x2c, 0xf6, 0x87, 0x12}}
+	return a, nil
+}
+
+var _schemaPlanResponseJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\x91\x4f\x4f\x84\x30\x10\xc5\xef\x7c\x8a\x09\x9a\xec\x45\x40\x4f\x26\x7b\x35\xde\x8d\x57\xe3\xa1\x96\x01\xba\x81\x4e\x9d\x0e\x26\xb8\xe1\xbb\x4b\x29\x6c\xc0\x35\xb1\xa7\xe6\xd7\xf7\x5e\xe7\xcf\x39\x81\xe9\xa4\xb7\x5e\x37\xd8\xa9\xf4\x08\x69\x23\xe2\x8e\x45\x71\xf2\x64\xb3\x48\x73\xe2\xba\x28\x59\x55\x92\xdd\x3f\x16\x91\xdd\xa4\x77\x8b\xd3\x94\x1b\x57\x6d\xe4\xbb\x77\xb9\xa6\x6e\xd1\x15\x5f\x0f\x85\x6b\x95\xcd\x19\xbd\x23\xeb\x31\x0f\xc1\xab\xb9\x44\xaf\xd9\x38\x31\x13\x9a\x42\x5e\xd1\x53\xcf\x1a\xc1\x31\x09\x69\x6a\xe1\x10\xbc\x07\xb8\x98\x57\xa3\x0c\x0e\x83\x83\x3e\x4e\xa8\x65\xa5\xaa\x2c\x4d\xc8\x52\xed\x0b\x93\x43\x16\x83\x7e\x52\x55\xaa\xf5\xb8\x48\x18\x3f\x7b\xc3\x18\x6a\x7e\x9b\xc9\x4c\x75\xa3\x6c\x3d\x69\x67\xf2\xbe\x48\xdd\x36\xe3\x7c\x2d\xde\xc2\xbf\xba\x79\x8a\x3a\x58\xbf\x04\x21\x10\x56\xd6\xcf\x35\x82\x34\x18\xfa\x8a\x0d\x57\x4c\x1d\x18\xf1\xa0\x7b\x66\xb4\x02\x5e\x94\x60\x70\x04\x38\x05\xcf\x01\x33\xcc\xe1\xb9\x73\x32\x80\xa9\xf6\x11\xc6\x43\xef\x32\xa1\xac\x0c\xa2\x65\x24\x97\xda\xd6\x81\x29\x66\x35\xfc\x7e\x34\x82\xdd\x75\x3f\x71\xbd\x8c\xd5\x7f\xfb\x8d\x03\x89\x8b\xdd\x25\x8c\xc9\xfe\x36\x26\x63\xf2\x03\xec\xa2\x76\xf8\x71\x02\x00\x00")
+
+func schemaPlanResponseJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_schemaPlanResponseJson,
+		"schema/plan.response.json",
+	)
+}
+
+func schemaPlanResponseJson() (*asset, error) {
+	bytes, err := schemaPlanResponseJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "schema/plan.response.json", size: 625, mode: os.FileMode(420), modTime: time.Unix(1792195963, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xff, 0xd3, 0x30, 0xc1, 0x4b, 0x53, 0xdd, 0x5d, 0x8e, 0xc6, 0xae, 0xb8, 0xb2, 0x66, 0xc3, 0x5c, 0xf1, 0x36, 0xe6, 0xc4, 0xc0, 0x86, 0xf3, 0xc2, 0xc5, 0xb1, 0xc4, 0xc7, 0x12, 0x13, 0xf9, 0xc2}}
+	return a, nil
+}
+
+var _schemaResourceJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb5\x56\x5b\x6f\xd3\x30\x14\x7e\xdf\xaf\xb0\xc2\xa4\x36\xac\x49\xda\x8d\x8b\xd6\x97\x31\x89\x17\x24\x18\x13\x42\x42\x62\x29\x93\xe7\x9c\x24\x86\xc4\x36\xb6\x03\xeb\xd6\xfd\x77\x8e\x73\x5b\x6f\x63\x45\x62\x55\x15\xd9\x3e\xdf\xf1\xb9\xf8\xf3\x97\xdc\xee\x11\xfc\x79\xfb\x86\xe5\x50\x52\x6f\x4a\xbc\xdc\x5a\x35\x8d\xa2\xef\x46\x8a\xa0\x59\x0d\xa5\xce\xa2\x44\xd3\xd4\x06\xe3\xd7\x51\xb3\xf6\xcc\x1b\xb5\x9e\x3c\x59\xf2\xca\xb8\xbd\xa9\x54\xc8\x64\xd9\xe2\xa2\x5f\x93\x48\x83\x91\x95\x66\x10\xba\x3d\x3b\xbf\x04\x0c\xd3\x5c\x59\x8e\x4b\xe8\x7f\x4a\x3a\x14\x31\x0a\x18\x4f\x39\xa3\xce\x16\x76\x78\x3b\x57\xe0\x80\xf2\xea\x3b\x30\xdb\xad\xd2\x24\xe1\x0e\x46\x8b\x73\x2d\x15\x68\xcb\xc1\x20\x2a\xa5\x85\x81\x16\xa2\xe1\x67\xc5\x35\xb8\x2c\x2f\xea\x95\xfb\xed\xea\xe9\xac\xc5\xa9\xe5\x0d\x6e\xd7\x90\xcb\x2b\xdb\xd2\xff\xd4\x25\xef\xd0\x21\xf9\x9c\x73\x43\xf0\x4f\xc9\x5b\xc9\x7e\x80\x26\xbc\xa4\x19\x60\x89\x29\x68\x10\x0c\x46\x44\xaa\x26\xed\x62\x4e\xb8\x60\x45\x95\x70\x91\x21\xdc\x52\x7c\x8a\x24\x92\x1a\x27\x09\xcf\xc0\x58\x32\x84\x2c\x24\xb1\xd7\xb4\x36\xca\x98\x0a\x30\x55\xd7\x84\xe9\x24\x1c\xc7\x1e\x41\x2c\x5a\x99\x0e\xb9\x8c\xca\x79\x67\x74\xc3\x3a\xea\x1b\x93\xd3\xc3\x97\xaf\xa6\x61\x18\xc6\x9e\xdf\xf5\x73\xbd\x3c\xcf\x58\x8d\x29\xac\x5b\x4b\x2e\xde\x83\xc8\x6c\x8e\x90\xa3\x35\x9b\xa2\xd6\x82\xae\xcb\xff\x36\x3c\x99\x5e\xd0\xe0\xe6\x34\xf8\x3a\x0e\x8e\x67\x2b\xb3\x60\xf6\x7c\xc9\xe4\x9f\xa0\x31\x8e\xc3\x5d\xd1\xfe\x73\x34\x4f\x2f\xdc\xe4\xc0\x3f\x89\xfc\x13\x67\xad\x67\xb8\xee\x3c\xc3\xcb\xd9\xe2\xf2\x72\x11\x1c\xf8\xbd\xa5\xf6\x89\x76\x02\xb6\xdb\xc7\xf1\xef\x99\x7b\x84\xc1\xec\x76\x3c\x9a\x1c\xbe\xbe\xab\xf3\xec\x5a\x87\x0e\xa9\x73\xb8\x7d\xf5\x02\x0d\xfb\x5e\xdf\x87\xbb\xfb\x96\x78\xaa\x2a\x8a\x73\x59\x70\x36\x7f\x94\x2d\x5f\x72\x10\xc4\x4a\xe2\x5c\x88\xcd\xa1\xe7\xfe\xc0\x34\x4c\x31\x53\x32\x38\x2d\x7e\xd3\xb9\x19\x8c\xc8\xe0\x5d\x7a\x26\xed\x39\x62\x40\xd8\x01\x19\x4a\xe1\x48\x93\x12\x21\x2d\x51\xcd\x2a\x29\x24\x73\x5c\xf2\x1d\x19\x06\x67\xf0\x0b\xf4\x20\x24\x6f\x21\xa5\x55\x61\x8d\x0b\xe5\xa2\xe0\xc6\xb8\x81\x69\xc2\xaa\x3a\xd5\x7f\xa4\x03\x88\xaa\x74\xf7\x08\xef\x6b\x9d\x9d\x37\x22\xde\x72\x76\x6e\x5e\x47\xf7\xc8\x6c\x6b\x93\x98\x14\x29\xcf\x76\xbf\x4e\x0d\xbe\xd2\x8d\x16\xf4\xf7\xaa\xae\xb9\xad\xaa\xd7\x8d\x95\xab\x26\x05\x81\x6b\x60\xd5\xb2\x86\x6c\x14\xb9\xa2\x25\xbd\xf5\x01\x4d\xb1\xba\x82\xad\x35\x15\xbc\xe4\xd6\xec\x5e\x53\x83\x27\x32\x5d\x3f\x7a\xca\x1c\xd6\xd5\x6c\x29\x17\xa0\x0d\xca\x04\xf6\x52\xf3\x5a\x1c\x1c\xb8\x75\x4d\x80\x15\x14\x25\x8d\x5c\xcd\x57\x5b\x80\x46\x28\x52\xa7\x20\x2b\x07\x9e\x34\x44\x68\xdd\x37\xda\xb1\x8f\xba\xf4\x98\x86\xb7\xae\xb5\x82\x6f\xed\x82\xe5\x25\xc8\x6a\x87\x3e\x7c\xa0\xd7\xbc\xac\x4a\x92\xb4\xa7\xea\x1a\x01\x94\xe5\x0f\x36\xc4\x74\x0a\x78\x3c\x36\x31\x32\x2c\xf6\x26\xe3\xb2\x1d\xe4\x47\x6e\xe8\x6f\x34\xaa\xcb\xe6\xb1\x56\xfd\x1f\x6a\x2c\xbd\x6e\xee\xe5\x60\xfb\xeb\xa4\xb7\x73\xc1\xad\xb3\x6c\x5e\xb9\x35\x65\x6d\xb4\x6f\xe8\x34\xb3\x55\xc1\xa1\x30\x8b\xca\x2c\x4a\xb3\xc0\xc7\x22\xf7\xfd\x83\x7d\x6f\xf9\x2c\xfa\x18\xc6\x52\x0b\x4f\x1d\x44\x15\x54\x3c\x75\x0c\xaa\x54\x31\xff\xaf\x41\x56\x62\xdc\x6d\x65\x74\x02\x0a\x44\x62\x3e\x8a\x47\x29\x7d\x46\x4b\xa8\x2f\xb4\x44\x8a\xe9\x9e\x63\x28\x55\xa2\x26\x9d\x41\x3b\xb9\xaa\x78\x91\x10\xf7\x35\xe2\x5e\xea\x36\xa7\x96\x94\x15\x8e\xae\xf0\x9e\x62\x79\xdc\x71\x14\x52\xa9\x91\xbc\x4e\xe5\xfa\x8f\xa6\x87\x18\x4a\xb5\xa6\xf3\x75\x63\x25\x38\xee\xff\xce\x42\xd9\x49\xd6\x1a\x80\xb7\xa6\x2d\x8c\xfc\xab\xf4\x6f\x7c\x0d\x4c\x1e\xea\xe0\x5e\xf3\xbc\xdb\xfb\x03\xf9\xba\xd2\x13\x5b\x0a\x00\x00")
 
 func schemaResourceJsonBytes() ([]byte, error) {
 	return bindataRead(
@@ -189,8 +338,48 @@ func schemaResourceJson() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "schema/resource.json", size: 685, mode: os.FileMode(420), modTime: time.Unix(1541191992, 0)}
-	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x8a, 0x17, 0x74, 0xd3, 0x6, 0xa2, 0x18, 0x7f, 0xc8, 0x43, 0x30, 0xbd, 0xd8, 0xf8, 0x43, 0xd5, 0x5d, 0x14, 0xa7, 0x7b, 0x79, 0x44, 0x33, 0x81, 0x76, 0x19, 0x6b, 0xe5, 0xf2, 0xd1, 0x7d, 0xae}}
+	info := bindataFileInfo{name: "schema/resource.json", size: 2651, mode: os.FileMode(420), modTime: time.Unix(1792197131, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xd9, 0x4d, 0x5a, 0xfa, 0xc4, 0xcf, 0x2c, 0x4c, 0xa6, 0x42, 0xa2, 0xfa, 0x3c, 0x20, 0x6b, 0x64, 0x57, 0xfe, 0x60, 0xe8, 0xee, 0xc8, 0xe5, 0x7d, 0x83, 0xa2, 0x20, 0x36, 0x15, 0xc1, 0x18, 0xa3}}
+	return a, nil
+}
+
+var _schemaStateRequestJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xc5\x56\xcb\x6e\xdb\x30\x10\xbc\xfb\x2b\x08\xb5\x40\x5a\xd4\xb2\x92\x53\xd1\xdc\x02\xf4\x12\xa0\x87\xa2\xc7\x1a\x3e\x30\xe2\x4a\x66\x20\x89\x2c\xb9\x34\xe0\x04\xfe\xf7\x92\x7a\xbf\x58\xdb\x71\xe0\xfa\x20\x40\xbb\x9c\xe1\xce\x72\x87\xf2\xeb\x82\xd8\x5f\xf0\x51\xc7\x5b\xc8\x69\x70\x4f\x82\x2d\xa2\xbc\x8f\xa2\x67\x2d\x8a\xb0\x8a\xae\x84\x4a\x23\xa6\x68\x82\xe1\xed\xd7\xa8\x8a\x7d\x08\x96\x35\x92\xb3\x1e\x2a\xe5\xf8\x62\xe4\x2a\x16\x79\xbd\x2e\xda\xdd\x45\x1a\x29\xc2\x4a\xc1\x1f\x03\x1a\x57\x8e\xb8\x01\x33\xd0\xb1\xe2\x12\xb9\x0d\x59\x92\x5f\xa0\x85\x51\x31\x10\xa9\x04\x8a\x58\x64\xe4\xa6\xc4\xde\x90\x06\xdc\x00\x71\x2f\xc1\x21\xc4\xd3\x33\xc4\xd8\x44\x29\x63\xdc\x71\xd1\xec\xa7\x12\x12\x14\x72\xd0\x76\x55\x42\x33\x0d\xf5\x12\x47\xc4\x15\xb8\x9a\xd7\x65\xa4\x8d\x5a\xfa\x47\x16\x2c\xdd\x4b\x55\x45\x50\xe6\x37\x35\x50\xf6\x19\x5f\xe7\xa0\xfd\xf0\xa0\x48\x8d\x8a\x17\x69\x5d\x64\x9b\xcd\x79\xf1\x03\x8a\x14\xb7\x76\xc9\x5d\x9b\x3a\x2c\xfb\xd4\x75\x21\x5e\xe6\x81\xfc\x36\x7b\xbc\x0d\x83\xea\x27\xed\x68\xb3\x05\xcd\x61\x44\xde\x6d\x3f\x08\x6f\x46\xbc\x9e\x6e\x0d\x99\xe7\x32\xc7\x1b\xd7\xed\x41\x11\x41\x95\x83\xb3\xa6\xe1\xcb\x66\x1d\xda\xe7\x43\xf8\xfb\x36\xfc\xb6\xf9\x12\x4c\x30\x07\x9f\x90\x0b\xcb\x98\x3f\xc7\x7f\xed\x1a\x8b\x22\xe1\xa9\x7f\x5f\x9f\x2b\x2a\x9c\x51\xd4\x65\x96\x84\x6a\xe7\x93\x1d\x67\xc0\x08\x2f\x08\x6e\x81\x3c\x19\x9e\xb1\xb1\x59\xbc\xba\x66\xa7\xe7\xd8\x14\xa1\x32\x30\x55\xb9\x98\x7f\xeb\x4f\xb3\x54\xb0\xe3\xc2\x4c\x07\x62\xaa\x37\x16\x8a\x11\x91\x94\x92\x1a\x13\x38\xb9\x36\xc4\x51\x93\x8c\x6a\x24\xda\xc4\x31\x68\x9d\x98\x8c\x50\x29\xb3\x3d\xf9\xc4\x13\x42\x8b\xfd\xe7\xb1\xec\x6b\x99\xc5\x9e\x85\xa7\x9d\x9d\x91\x7d\x23\x38\x8b\x69\xaf\xa4\x69\xd2\x29\xe6\xc0\x1e\xf0\x32\x17\x36\x25\x9f\xea\x80\x93\xa6\xdb\x7b\x6b\x5d\xc4\x7a\x96\x53\x4f\xac\xd3\x77\x71\xfb\x06\xf3\xf1\x7b\x33\x94\x03\x9f\xd9\x08\xc5\x6a\x28\xeb\x73\x19\x0c\xee\x51\x1b\x9e\x53\x74\x77\xf0\x17\xde\x5a\x89\x50\x39\x75\x2c\x01\xb3\x1f\xd8\x10\x79\x0e\xc1\xf5\x2e\xaf\xda\xcc\xae\x4d\x6d\xdf\xf6\xd7\xbc\xaf\x66\x84\x95\xff\x34\xce\xd7\x55\xc2\xde\xa4\x67\xdd\x29\xb2\x1f\x44\x93\x65\xc1\xd8\xc0\xef\xa6\x4d\x18\x94\x06\xf5\xf9\xea\x6a\xe0\x7f\x3f\x2f\xdf\xf7\x65\x51\x3d\x0f\x8b\xbf\x2a\x90\xa2\xed\xc8\x0a\x00\x00")
+
+func schemaStateRequestJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_schemaStateRequestJson,
+		"schema/state.request.json",
+	)
+}
+
+func schemaStateRequestJson() (*asset, error) {
+	bytes, err := schemaStateRequestJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "schema/state.request.json", size: 2760, mode: os.FileMode(420), modTime: time.Unix(1792196465, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xc9, 0x5d, 0xf7, 0xe9, 0x8c, 0x6e, 0x4a, 0x14, 0xb9, 0x7e, 0x12, 0x87, 0x9b, 0x46, 0x4c, 0x25, 0xdd, 0xbd, 0xbd, 0x6e, 0x13, 0x5c, 0x88, 0xbd, 0xd8, 0xc6, 0xc9, 0x5a, 0x69, 0x47, 0x85, 0xaa}}
+	return a, nil
+}
+
+var _schemaStateResponseJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x95\x52\xcb\x6e\x1b\x31\x0c\xbc\xfb\x2b\x08\x35\x40\x5a\x20\xd6\xb6\xa7\x02\x69\x90\x4b\x7f\xa0\x08\x90\x5e\x92\x1e\x94\x15\xd7\x56\xb0\x16\x55\x92\x4a\xe2\x1a\xfe\xf7\x6a\x9f\xf1\x16\x0d\x8a\xea\x20\x48\xa3\x99\x21\x39\xbb\x87\x15\x94\x65\xce\xa4\xde\xe2\xce\x99\x4b\x30\x5b\xd5\x74\x59\x55\x8f\x42\x71\x3d\xa0\x96\x78\x53\x79\x76\x8d\xae\x3f\x7e\xae\x06\xec\x9d\xb9\x18\x95\xc1\x9f\xa8\x36\x41\x7f\xe5\x64\x6b\xda\x8d\xbc\xea\xe9\x53\x25\xea\x14\x2d\xa3\x24\x8a\x82\xb6\x73\x9e\xd4\x1e\xa5\xe6\x90\x34\x14\xa8\xb8\xdc\xa0\x50\xe6\x1a\x21\x31\x29\xd5\xd4\xc2\x79\x2f\x3e\x87\x59\x3d\x29\x75\x9f\xb0\x93\xd0\xc3\x23\xd6\x3a\xa1\xce\xfb\xd0\x99\xb9\xf6\x1b\x53\x42\xd6\x80\x52\x58\x8d\x6b\x05\x47\x0a\xe3\xcf\x1c\x18\xbb\xae\xef\x7a\xa4\x47\xfb\x32\xa6\xbf\xff\x18\x89\xe9\xd4\xe1\xf0\x27\xf5\x14\xfa\xdb\x28\x5f\x33\x33\x46\x85\x9e\x0d\xd4\x80\x6e\xb1\x9b\xa2\x9f\xef\x02\x42\xec\x01\x71\xbb\xb2\x29\xe7\x5a\x33\x23\x38\x81\xa0\x02\x35\xc5\x26\x6c\x32\xbb\xce\xec\x0b\xc4\xdc\xb6\x10\x96\x0e\xe0\x09\x05\x22\x29\xe0\x4b\x10\x85\x3d\xea\x14\xcd\xdc\xd1\x18\xd1\xdd\x6b\x48\x60\x3a\x2f\x33\x8d\x38\x33\xdf\x88\xad\xf4\x85\x33\xf1\xf8\xaa\x31\x94\x35\x65\x95\x7f\x86\xf0\xdd\xb5\xb9\xb4\x89\x2f\x89\x04\x3d\x3c\xec\x97\x33\x34\xc4\x40\x05\xe1\x19\x12\x50\x2a\x97\x06\x4b\x76\x85\x30\xa4\x14\x78\x99\x08\xbc\xc7\x8d\x85\x7b\x73\x76\x98\x65\xf6\x2a\x96\x24\xaf\xed\xd8\x98\xbd\x1a\x0e\xd7\xc7\x7b\xf3\xc1\xc2\x6d\x57\xfc\x79\x8b\x71\x59\x3e\x08\xe4\xb4\x56\x5a\xfb\xee\x13\xb9\xe8\xcb\x73\x1e\x42\x75\x29\xb5\x01\xfd\x5b\x91\x2e\xff\xba\xff\x8b\x71\x35\xec\xc7\xd5\x6f\xa5\x90\xee\xc9\x7b\x03\x00\x00")
+
+func schemaStateResponseJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_schemaStateResponseJson,
+		"schema/state.response.json",
+	)
+}
+
+func schemaStateResponseJson() (*asset, error) {
+	bytes, err := schemaStateResponseJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "schema/state.response.json", size: 891, mode: os.FileMode(420), modTime: time.Unix(1792196221, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x9f, 0x67, 0xf, 0x4b, 0xbe, 0x69, 0xd1, 0xce, 0xf6, 0xc1, 0xec, 0x6a, 0x16, 0x7f, 0x81, 0x74, 0x61, 0xf8, 0xa5, 0x92, 0x5f, 0xf5, 0xe, 0x3, 0x51, 0x97, 0x3f, 0x54, 0xb, 0xeb, 0xae, 0x26}}
 	return a, nil
 }
 
@@ -287,15 +476,33 @@ func AssetNames() []string {
 var _bindata = map[string]func() (*asset, error){
 	"schema/action.json": schemaActionJson,
 
+	"schema/apply.request.json": schemaApplyRequestJson,
+
+	"schema/apply.response.json": schemaApplyResponseJson,
+
 	"schema/build.request.json": schemaBuildRequestJson,
 
 	"schema/build.response.json": schemaBuildResponseJson,
 
+	"schema/change.json": schemaChangeJson,
+
+	"schema/event.json": schemaEventJson,
+
 	"schema/init.request.json": schemaInitRequestJson,
 
 	"schema/init.response.json": schemaInitResponseJson,
 
+	"schema/limits.json": schemaLimitsJson,
+
+	"schema/plan.request.json": schemaPlanRequestJson,
+
+	"schema/plan.response.json": schemaPlanResponseJson,
+
 	"schema/resource.json": schemaResourceJson,
+
+	"schema/state.request.json": schemaStateRequestJson,
+
+	"schema/state.response.json": schemaStateResponseJson,
 }
 
 // AssetDir returns the file names below a certain
@@ -341,11 +548,20 @@ type bintree struct {
 var _bintree = &bintree{nil, map[string]*bintree{
 	"schema": &bintree{nil, map[string]*bintree{
 		"action.json":         &bintree{schemaActionJson, map[string]*bintree{}},
+		"apply.request.json":  &bintree{schemaApplyRequestJson, map[string]*bintree{}},
+		"apply.response.json": &bintree{schemaApplyResponseJson, map[string]*bintree{}},
 		"build.request.json":  &bintree{schemaBuildRequestJson, map[string]*bintree{}},
 		"build.response.json": &bintree{schemaBuildResponseJson, map[string]*bintree{}},
+		"change.json":         &bintree{schemaChangeJson, map[string]*bintree{}},
+		"event.json":          &bintree{schemaEventJson, map[string]*bintree{}},
 		"init.request.json":   &bintree{schemaInitRequestJson, map[string]*bintree{}},
 		"init.response.json":  &bintree{schemaInitResponseJson, map[string]*bintree{}},
+		"limits.json":         &bintree{schemaLimitsJson, map[string]*bintree{}},
+		"plan.request.json":   &bintree{schemaPlanRequestJson, map[string]*bintree{}},
+		"plan.response.json":  &bintree{schemaPlanResponseJson, map[string]*bintree{}},
 		"resource.json":       &bintree{schemaResourceJson, map[string]*bintree{}},
+		"state.request.json":  &bintree{schemaStateRequestJson, map[string]*bintree{}},
+		"state.response.json": &bintree{schemaStateResponseJson, map[string]*bintree{}},
 	}},
 }}
 
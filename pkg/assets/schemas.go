@@ -4,24 +4,43 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/go-errors/errors"
 	"github.com/xeipuuv/gojsonschema"
 )
 
 var actionSchema = loadSchema("schema/action.json")
-var resourceSchema = loadSchema("schema/resource.json")
-var buildRequestSchema = loadSchema("schema/build.request.json", "schema/resource.json")
-var buildResponseSchema = loadSchema("schema/build.response.json")
+var changeSchema = loadSchema("schema/change.json")
+var eventSchema = loadSchema("schema/event.json")
+var limitsSchema = loadSchema("schema/limits.json")
+var resourceSchema = loadSchema("schema/resource.json", "schema/limits.json")
+var buildRequestSchema = loadSchema("schema/build.request.json", "schema/resource.json", "schema/limits.json")
+var buildResponseSchema = loadSchema("schema/build.response.json", "schema/change.json")
 var initRequestSchema = loadSchema("schema/init.request.json")
-var initResponseSchema = loadSchema("schema/init.response.json", "schema/action.json")
+var initResponseSchema = loadSchema("schema/init.response.json", "schema/action.json", "schema/limits.json")
+var stateRequestSchema = loadSchema("schema/state.request.json")
+var stateResponseSchema = loadSchema("schema/state.response.json")
+var planRequestSchema = loadSchema("schema/plan.request.json")
+var planResponseSchema = loadSchema("schema/plan.response.json", "schema/change.json")
+var applyRequestSchema = loadSchema("schema/apply.request.json", "schema/change.json")
+var applyResponseSchema = loadSchema("schema/apply.response.json")
 
 func GetActionSchema() *Schema        { return actionSchema }
+func GetChangeSchema() *Schema        { return changeSchema }
+func GetEventSchema() *Schema         { return eventSchema }
+func GetLimitsSchema() *Schema        { return limitsSchema }
 func GetResourceSchema() *Schema      { return resourceSchema }
 func GetBuildRequestSchema() *Schema  { return buildRequestSchema }
 func GetBuildResponseSchema() *Schema { return buildResponseSchema }
 func GetInitRequestSchema() *Schema   { return initRequestSchema }
 func GetInitResponseSchema() *Schema  { return initResponseSchema }
+func GetStateRequestSchema() *Schema  { return stateRequestSchema }
+func GetStateResponseSchema() *Schema { return stateResponseSchema }
+func GetPlanRequestSchema() *Schema   { return planRequestSchema }
+func GetPlanResponseSchema() *Schema  { return planResponseSchema }
+func GetApplyRequestSchema() *Schema  { return applyRequestSchema }
+func GetApplyResponseSchema() *Schema { return applyResponseSchema }
 
 // Compiled JSON schema.
 type Schema struct {
@@ -140,6 +159,12 @@ func (schema *Schema) ParseAndValidate(target interface{}, inputBytes []byte) (e
 
 // Validate that the given source complies with this schema.
 func (schema *Schema) Validate(source interface{}) (err error) {
+	return schema.ValidateIgnoring(source)
+}
+
+// Validate that the given source complies with this schema, ignoring violations of the values at the given JSON
+// pointers (or nested in them). This is useful for validating documents in which some values are not known yet.
+func (schema *Schema) ValidateIgnoring(source interface{}, ignoredPointers ...string) (err error) {
 	var result *gojsonschema.Result
 
 	switch source := source.(type) {
@@ -160,10 +185,25 @@ func (schema *Schema) Validate(source interface{}) (err error) {
 	} else if !result.Valid() {
 		var msg = ""
 		for _, e := range result.Errors() {
-			msg += fmt.Sprintf("\t- %s\n", e.String())
+			if !isIgnored(e, ignoredPointers) {
+				msg += fmt.Sprintf("\t- %s\n", e.String())
+			}
+		}
+		if msg != "" {
+			return errors.New(fmt.Sprintf("JSON validation failed:\n%s", msg))
 		}
-		return errors.New(fmt.Sprintf("JSON validation failed:\n%s", msg))
 	}
 
 	return nil
 }
+
+// Returns true if the given validation error concerns a value at (or nested in) any of the given JSON pointers.
+func isIgnored(e gojsonschema.ResultError, ignoredPointers []string) bool {
+	pointer := strings.TrimPrefix(e.Context().String("/"), "(root)")
+	for _, ignored := range ignoredPointers {
+		if pointer == ignored || strings.HasPrefix(pointer, ignored+"/") {
+			return true
+		}
+	}
+	return false
+}
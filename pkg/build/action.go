@@ -3,10 +3,10 @@ package build
 import (
 	"context"
 	"fmt"
-	"github.com/gitzup/agent/internal/docker"
 	. "github.com/gitzup/agent/internal/logger"
 	"github.com/gitzup/agent/pkg/assets"
 	"github.com/go-errors/errors"
+	"path/filepath"
 	"time"
 )
 
@@ -16,15 +16,45 @@ type Action interface {
 	Image() string
 	Entrypoint() []string
 	Cmd() []string
+	Timeout() time.Duration
+	PullPolicy() PullPolicy
+	Limits() Limits
+	Network() string
+	Events() []Event
+	Artifacts() []Artifact
+	Digest() string
 	Invoke(ctx context.Context, input interface{}, outputSchema *assets.Schema, output interface{}) error
 }
 
+// Path of the file in the action's container to which the action writes its response.
+const actionResultPath = "/gitzup/result.json"
+
+// Path in the action's container at which the resource's workspace is mounted.
+const actionWorkspacePath = "/gitzup/workspace"
+
+// Network modes of actions.
+const (
+	// The action is attached to the build request's network (the default).
+	ActionNetworkBuild = "build"
+
+	// The action runs with networking disabled.
+	ActionNetworkNone = "none"
+)
+
 type actionImpl struct {
+	runtime    Runtime
 	resource   Resource
 	name       string
 	image      string
 	entrypoint []string
 	cmd        []string
+	timeout    time.Duration
+	pullPolicy PullPolicy
+	limits     Limits
+	network    string
+	digest     string
+	events     eventLog
+	artifacts  artifactList
 }
 
 func (act *actionImpl) Resource() Resource {
@@ -47,17 +77,53 @@ func (act *actionImpl) Cmd() []string {
 	return act.cmd
 }
 
+func (act *actionImpl) Timeout() time.Duration {
+	return act.timeout
+}
+
+func (act *actionImpl) PullPolicy() PullPolicy {
+	return act.pullPolicy
+}
+
+func (act *actionImpl) Limits() Limits {
+	return act.limits
+}
+
+// Returns the network mode of this action (see ActionNetworkBuild & ActionNetworkNone).
+func (act *actionImpl) Network() string {
+	if act.network == "" {
+		return ActionNetworkBuild
+	}
+	return act.network
+}
+
+// Returns the events reported by this action while it ran (if it was invoked).
+func (act *actionImpl) Events() []Event {
+	return act.events.Events()
+}
+
+// Returns the artifacts handed back by this action (if it was invoked & succeeded).
+func (act *actionImpl) Artifacts() []Artifact {
+	return act.artifacts.Artifacts()
+}
+
+// Returns the digest of the image used by the last invocation of this action, or an empty string if it was never
+// invoked.
+func (act *actionImpl) Digest() string {
+	return act.digest
+}
+
 func (act *actionImpl) Invoke(ctx context.Context, input interface{}, outputSchema *assets.Schema, output interface{}) error {
 	ctx = context.WithValue(ctx, "resource", act.resource.Name())
 	ctx = context.WithValue(ctx, "action", act.Name())
 
 	From(ctx).Infof("Invoking action '%s'", act.Name())
 
-	// TODO: parametrize "progress"
-	err := docker.Pull(ctx, false, act.Image())
+	digest, err := act.runtime.Pull(ctx, act.Image(), act.PullPolicy())
 	if err != nil {
 		return err
 	}
+	act.digest = digest
 
 	// container name
 	containerName := fmt.Sprintf("%s-%s-%s", act.Resource().Request().Id(), act.Resource().Name(), act.Name())
@@ -68,23 +134,101 @@ func (act *actionImpl) Invoke(ctx context.Context, input interface{}, outputSche
 		fmt.Sprintf("GITZUP_RESOURCE_NAME=%s", act.Resource().Name()),
 		fmt.Sprintf("GITZUP_RESOURCE_TYPE=%s", act.Resource().Type()),
 		fmt.Sprintf("GITZUP_ACTION_NAME=%s", act.Name()),
+		fmt.Sprintf("GITZUP_WORKSPACE=%s", actionWorkspacePath),
 	}
 
-	// volumes
+	// volumes & mounts (all actions of the resource share the resource's workspace)
 	volumes := map[string]struct{}{}
+	mounts := []Mount{{Source: act.Resource().WorkspacePath(), Target: actionWorkspacePath}}
+
+	// result handler, which also collects the action's artifacts (if any) once its result is parsed successfully
+	parseResult := act.createResultParser(outputSchema, &output)
+	handler := func(ctx context.Context, containerID string) error {
+		if err := parseResult(ctx, containerID); err != nil {
+			return err
+		}
+		return act.collectArtifacts(ctx, containerID)
+	}
 
-	// result handler
-	handler := docker.CreateJsonResultParser("/gitzup/result.json", outputSchema, &output)
-
-	// create a timeout context
-	// TODO: support timeout provided by resource itself (eg. from state action)
-	runCtx, runCtxCancelFunc := context.WithTimeout(ctx, 5*time.Second)
+	// create a timeout context (a zero timeout means no limit)
+	runCtx, runCtxCancelFunc := context.WithCancel(ctx)
+	if act.Timeout() > 0 {
+		runCtx, runCtxCancelFunc = context.WithTimeout(ctx, act.Timeout())
+	}
 	defer runCtxCancelFunc()
 
-	// execute Docker image for this action
-	if err = docker.Run(runCtx, act.Image(), containerName, env, volumes, input, nil, handler); err != nil {
-		return errors.WrapPrefix(err, fmt.Sprintf("action '%s' failed", act.Name()), 0)
+	// label the container, so it can be traced back to this action (and garbage collected if left behind)
+	labels := act.Resource().Request().(*requestImpl).labels()
+	labels[LabelResource] = act.Resource().Name()
+	labels[LabelAction] = act.Name()
+
+	// attach the action to the build request's network, unless it requires no networking
+	network := act.Resource().Request().(*requestImpl).networkID
+	if act.Network() == ActionNetworkNone {
+		network = NetworkNone
+	}
+
+	// capture the action's output in the build request's workspace
+	logsPath := filepath.Join(act.Resource().Request().WorkspacePath(), actionLogsDir, act.Resource().Name())
+	tailLines := act.Resource().Request().(*requestImpl).options.OutputTailLines
+	logs, err := newActionOutput(ctx, logsPath, act.Name(), tailLines, act.events.add)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		logs.Flush()
+		if err := logs.Close(); err != nil {
+			From(ctx).WithError(err).Warnf("Failed closing output of action '%s'", act.Name())
+		}
+	}()
+
+	// execute the container for this action
+	spec := &ContainerSpec{
+		Name:       containerName,
+		Image:      act.Image(),
+		Entrypoint: act.Entrypoint(),
+		Cmd:        act.Cmd(),
+		Env:        env,
+		Volumes:    volumes,
+		Mounts:     mounts,
+		Labels:     labels,
+		User:       act.Resource().Request().(*requestImpl).options.ContainerUser,
+		Limits:     act.Limits(),
+		Network:    network,
+		Input:      input,
+		Stdout:     logs.Stdout,
+		Stderr:     logs.Stderr,
+	}
+	if err = act.runtime.Run(runCtx, spec, nil, handler); err != nil {
+		if ctx.Err() == context.Canceled {
+			return errors.Errorf("action '%s' cancelled", act.Name())
+		} else if ctx.Err() == context.DeadlineExceeded {
+			return errors.Errorf("action '%s' interrupted (build timeout)", act.Name())
+		} else if runCtx.Err() == context.DeadlineExceeded {
+			err = errors.Errorf("action '%s' timed out after %s (action timeout)", act.Name(), act.Timeout())
+			return withOutputTail(err, logs.Tail())
+		}
+		return withOutputTail(errors.WrapPrefix(err, fmt.Sprintf("action '%s' failed", act.Name()), 0), logs.Tail())
 	}
 
 	return nil
 }
+
+// Creates a container handler which reads the action's response from its container, and parses & validates it into the
+// given response value.
+func (act *actionImpl) createResultParser(schema *assets.Schema, response interface{}) ContainerHandler {
+	return func(ctx context.Context, containerID string) error {
+
+		// read JSON response from resource (fail if missing or invalid)
+		b, err := act.runtime.CopyFrom(ctx, containerID, actionResultPath)
+		if err != nil {
+			return errors.WrapPrefix(err, fmt.Sprintf("failed reading '%s' from container", actionResultPath), 0)
+		}
+		err = schema.ParseAndValidate(response, b)
+		if err != nil {
+			return errors.WrapPrefix(err, fmt.Sprintf("response from container is illegal"), 0)
+		}
+
+		return nil
+	}
+}
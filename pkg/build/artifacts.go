@@ -0,0 +1,171 @@
+package build
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/go-errors/errors"
+)
+
+// Path in the action's container of the directory in which the action may place artifacts (files to hand back).
+const actionArtifactsPath = "/gitzup/artifacts"
+
+// Directory (relative to a build request's workspace) into which the artifacts of actions are extracted, each action's
+// artifacts in a "<resource>/<action>" sub-directory. Unlike resource workspaces, the build request's workspace is not
+// mounted into action containers, so actions cannot tamper with it (eg. by planting symbolic links).
+const actionArtifactsDir = ".artifacts"
+
+// A file handed back by an action.
+type Artifact struct {
+	// Name of the action that produced the artifact.
+	Action string `json:"action"`
+
+	// Path of the artifact, relative to the action's artifacts directory.
+	Path string `json:"path"`
+
+	// Size of the artifact, in bytes.
+	Size int64 `json:"size"`
+
+	// SHA-256 checksum of the artifact, hex-encoded.
+	SHA256 string `json:"sha256"`
+}
+
+// Artifacts extracted from an action's container, in extraction order.
+type artifactList struct {
+	mutex     sync.Mutex
+	artifacts []Artifact
+}
+
+func (list *artifactList) set(artifacts []Artifact) {
+	list.mutex.Lock()
+	defer list.mutex.Unlock()
+	list.artifacts = artifacts
+}
+
+// Returns the extracted artifacts.
+func (list *artifactList) Artifacts() []Artifact {
+	list.mutex.Lock()
+	defer list.mutex.Unlock()
+	return append([]Artifact(nil), list.artifacts...)
+}
+
+// Extracts the artifacts of the given action from the given tar archive (as returned by Runtime.CopyArchiveFrom for
+// the artifacts directory) into the given directory. Fails if any artifact exceeds the given maximum file size, or if
+// all artifacts together exceed the given maximum total size (zero means no limit). Only regular files & directories
+// are extracted; links and other special files are skipped.
+func extractArtifacts(ctx context.Context, action string, archive io.Reader, dir string, maxFileSize int64, maxTotalSize int64) ([]Artifact, error) {
+	var artifacts []Artifact
+	var totalSize int64
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return artifacts, nil
+		} else if err != nil {
+			return artifacts, errors.WrapPrefix(err, "failed reading artifacts archive", 0)
+		}
+
+		// entries are rooted at the artifacts directory's base name, which we strip
+		name := strings.TrimPrefix(header.Name, "/")
+		slash := strings.Index(name, "/")
+		if slash < 0 {
+			continue
+		}
+		name = path.Clean(name[slash+1:])
+		if name == "." {
+			continue
+		} else if name == ".." || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			return artifacts, errors.Errorf("illegal artifact path '%s'", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return artifacts, errors.WrapPrefix(err, fmt.Sprintf("failed creating artifacts directory '%s'", name), 0)
+			}
+			continue
+		case tar.TypeReg, tar.TypeRegA:
+		default:
+			From(ctx).Warnf("Skipping artifact '%s' (only regular files are supported)", name)
+			continue
+		}
+
+		if maxFileSize > 0 && header.Size > maxFileSize {
+			return artifacts, errors.Errorf("artifact '%s' exceeds the maximum artifact size of %s", name, FormatMemory(maxFileSize))
+		} else if maxTotalSize > 0 && totalSize+header.Size > maxTotalSize {
+			return artifacts, errors.Errorf("artifacts of action '%s' exceed the maximum total size of %s", action, FormatMemory(maxTotalSize))
+		}
+		totalSize += header.Size
+
+		artifact, err := extractArtifact(tr, target, header.Size)
+		if err != nil {
+			return artifacts, errors.WrapPrefix(err, fmt.Sprintf("failed extracting artifact '%s'", name), 0)
+		}
+		artifact.Action, artifact.Path = action, name
+		artifacts = append(artifacts, *artifact)
+	}
+}
+
+// Writes the given number of bytes from the given reader to the given file, computing its checksum.
+func extractArtifact(reader io.Reader, target string, size int64) (*Artifact, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	//noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	hash := sha256.New()
+	written, err := io.CopyN(io.MultiWriter(file, hash), reader, size)
+	if err != nil {
+		return nil, err
+	} else if err := file.Close(); err != nil {
+		return nil, err
+	}
+	return &Artifact{Size: written, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// Copies the action's artifacts (if any) out of the given container into the build request's workspace, replacing any
+// artifacts of a previous invocation.
+func (act *actionImpl) collectArtifacts(ctx context.Context, containerID string) error {
+	archive, err := act.runtime.CopyArchiveFrom(ctx, containerID, actionArtifactsPath)
+	if err != nil {
+		return err
+	} else if archive == nil {
+		return nil
+	}
+	//noinspection GoUnhandledErrorResult
+	defer archive.Close()
+
+	dir := filepath.Join(act.Resource().Request().WorkspacePath(), actionArtifactsDir, act.Resource().Name(), act.Name())
+	if err := os.RemoveAll(dir); err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed removing previous artifacts in '%s'", dir), 0)
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed creating artifacts directory '%s'", dir), 0)
+	}
+
+	options := act.Resource().Request().(*requestImpl).options
+	artifacts, err := extractArtifacts(ctx, act.Name(), archive, dir, options.MaxArtifactSize, options.MaxArtifactsSize)
+	act.artifacts.set(artifacts)
+	if err != nil {
+		return err
+	}
+	for _, artifact := range artifacts {
+		From(ctx).WithField("size", artifact.Size).WithField("sha256", artifact.SHA256).Infof("Collected artifact '%s'", artifact.Path)
+	}
+	return nil
+}
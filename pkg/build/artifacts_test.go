@@ -0,0 +1,158 @@
+package build
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	content  string
+}
+
+// Returns a tar archive of the given entries, as returned by Runtime.CopyArchiveFrom for the artifacts directory.
+func newArtifactsArchive(t *testing.T, entries ...tarEntry) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Mode: 0644, Size: int64(len(entry.content))}
+		if entry.typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if entry.typeflag == tar.TypeSymlink {
+			header.Linkname = entry.content
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			if _, err := tw.Write([]byte(entry.content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func newArtifactsDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gitzup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestExtractArtifacts(t *testing.T) {
+	dir := newArtifactsDir(t)
+	defer os.RemoveAll(dir)
+
+	archive := newArtifactsArchive(t,
+		tarEntry{name: "artifacts/", typeflag: tar.TypeDir},
+		tarEntry{name: "artifacts/report.txt", typeflag: tar.TypeReg, content: "hello"},
+		tarEntry{name: "artifacts/nested/", typeflag: tar.TypeDir},
+		tarEntry{name: "artifacts/nested/data.json", typeflag: tar.TypeReg, content: "{}"},
+	)
+	artifacts, err := extractArtifacts(context.Background(), "apply", archive, dir, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %v", artifacts)
+	}
+	report := artifacts[0]
+	if report.Action != "apply" || report.Path != "report.txt" || report.Size != 5 {
+		t.Errorf("unexpected artifact: %+v", report)
+	} else if report.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("unexpected checksum of artifact '%s': %s", report.Path, report.SHA256)
+	}
+	if artifacts[1].Path != "nested/data.json" {
+		t.Errorf("expected nested artifact, got %+v", artifacts[1])
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "nested", "data.json")); err != nil {
+		t.Errorf("expected nested artifact to be extracted: %s", err)
+	} else if string(b) != "{}" {
+		t.Errorf("unexpected content of nested artifact: %s", string(b))
+	}
+}
+
+func TestExtractArtifactsRejectsIllegalPaths(t *testing.T) {
+	for _, name := range []string{"artifacts/../escape.txt", "artifacts/nested/../../escape.txt", "artifacts//etc/escape.txt"} {
+		dir := newArtifactsDir(t)
+		archive := newArtifactsArchive(t, tarEntry{name: name, typeflag: tar.TypeReg, content: "gotcha"})
+		_, err := extractArtifacts(context.Background(), "apply", archive, filepath.Join(dir, "artifacts"), 0, 0)
+		if err == nil || !strings.Contains(err.Error(), "illegal artifact path") {
+			t.Errorf("expected illegal artifact path '%s' to be rejected, got: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !os.IsNotExist(err) {
+			t.Errorf("expected artifact '%s' not to be extracted outside the artifacts directory", name)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestExtractArtifactsSkipsNonRegularFiles(t *testing.T) {
+	dir := newArtifactsDir(t)
+	defer os.RemoveAll(dir)
+
+	archive := newArtifactsArchive(t,
+		tarEntry{name: "artifacts/link", typeflag: tar.TypeSymlink, content: "/etc/passwd"},
+		tarEntry{name: "artifacts/fifo", typeflag: tar.TypeFifo},
+		tarEntry{name: "artifacts/file.txt", typeflag: tar.TypeReg, content: "data"},
+	)
+	artifacts, err := extractArtifacts(context.Background(), "apply", archive, dir, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Path != "file.txt" {
+		t.Errorf("expected only the regular file to be extracted, got %v", artifacts)
+	}
+	for _, name := range []string{"link", "fifo"} {
+		if _, err := os.Lstat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected '%s' not to be extracted", name)
+		}
+	}
+}
+
+func TestExtractArtifactsEnforcesSizeLimits(t *testing.T) {
+	tests := []struct {
+		maxFileSize  int64
+		maxTotalSize int64
+		expected     string
+		extracted    int
+	}{
+		{maxFileSize: 0, maxTotalSize: 0, extracted: 2},
+		{maxFileSize: 6, maxTotalSize: 12, extracted: 2},
+		{maxFileSize: 5, maxTotalSize: 0, expected: "exceeds the maximum artifact size", extracted: 1},
+		{maxFileSize: 0, maxTotalSize: 10, expected: "exceed the maximum total size", extracted: 1},
+	}
+	for _, test := range tests {
+		dir := newArtifactsDir(t)
+		archive := newArtifactsArchive(t,
+			tarEntry{name: "artifacts/small.txt", typeflag: tar.TypeReg, content: "12345"},
+			tarEntry{name: "artifacts/large.txt", typeflag: tar.TypeReg, content: "123456"},
+		)
+		artifacts, err := extractArtifacts(context.Background(), "apply", archive, dir, test.maxFileSize, test.maxTotalSize)
+		if test.expected == "" && err != nil {
+			t.Errorf("unexpected error for limits %d/%d: %s", test.maxFileSize, test.maxTotalSize, err)
+		} else if test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)) {
+			t.Errorf("expected error containing '%s' for limits %d/%d, got: %v", test.expected, test.maxFileSize, test.maxTotalSize, err)
+		}
+		if len(artifacts) != test.extracted {
+			t.Errorf("expected %d artifacts to be extracted for limits %d/%d, got %v", test.extracted, test.maxFileSize, test.maxTotalSize, artifacts)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "large.txt")); test.expected != "" && !os.IsNotExist(err) {
+			t.Errorf("expected oversized artifact not to be extracted for limits %d/%d", test.maxFileSize, test.maxTotalSize)
+		}
+		os.RemoveAll(dir)
+	}
+}
@@ -0,0 +1,72 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/go-errors/errors"
+)
+
+// State store keeping records in an embedded BoltDB database file, using a bucket per project, keyed by resource name.
+type boltStateStore struct {
+	db *bolt.DB
+}
+
+// Creates a state store keeping records in the BoltDB database at the given file path (created if missing). The file is
+// locked for as long as the store is open.
+func NewBoltStateStore(file string) (StateStore, error) {
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed creating state database directory '%s'", path.Dir(file)), 0)
+	}
+	db, err := bolt.Open(file, 0644, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed opening state database '%s'", file), 0)
+	}
+	return &boltStateStore{db: db}, nil
+}
+
+func (store *boltStateStore) Get(project string, resource string) (*StateRecord, error) {
+	var record *StateRecord
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(project))
+		if bucket == nil {
+			return nil
+		}
+		b := bucket.Get([]byte(resource))
+		if b == nil {
+			return nil
+		}
+		record = &StateRecord{}
+		return json.Unmarshal(b, record)
+	})
+	if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed reading state of resource '%s'", resource), 0)
+	}
+	return record, nil
+}
+
+func (store *boltStateStore) Put(record *StateRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed serializing state of resource '%s'", record.Resource), 0)
+	}
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(record.Project))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(record.Resource), b)
+	})
+	if err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed writing state of resource '%s'", record.Resource), 0)
+	}
+	return nil
+}
+
+func (store *boltStateStore) Close() error {
+	return store.db.Close()
+}
@@ -0,0 +1,75 @@
+package build
+
+import (
+	"context"
+	"sync"
+
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/go-errors/errors"
+)
+
+// Limits the number of resources of each type processed concurrently, across all build requests sharing it (eg. to
+// protect a host or an external API from too many concurrent builds of a heavy resource type). Resource types without
+// a limit are not limited, but are still counted.
+type TypeLimiter struct {
+	limits   map[string]int
+	mutex    sync.Mutex
+	slots    map[string]chan struct{}
+	inFlight map[string]int
+}
+
+// Creates a limiter allowing up to the given number of concurrently processed resources of each type (keyed by
+// resource type). Limits lower than 1 are invalid.
+func NewTypeLimiter(limits map[string]int) (*TypeLimiter, error) {
+	slots := make(map[string]chan struct{}, len(limits))
+	for resourceType, limit := range limits {
+		if limit < 1 {
+			return nil, errors.Errorf("invalid concurrency limit %d for resource type '%s' (must be at least 1)", limit, resourceType)
+		}
+		slots[resourceType] = make(chan struct{}, limit)
+	}
+	return &TypeLimiter{limits: limits, slots: slots, inFlight: make(map[string]int)}, nil
+}
+
+// Waits until a resource of the given type may be processed, returning a function to call once it's done. Returns the
+// context's error if it's done first.
+func (limiter *TypeLimiter) acquire(ctx context.Context, resourceType string) (func(), error) {
+	if slots, ok := limiter.slots[resourceType]; ok {
+		select {
+		case slots <- struct{}{}:
+		default:
+			From(ctx).Infof("Waiting for one of %d resource(s) of type '%s' to finish", limiter.limits[resourceType], resourceType)
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return nil, errors.Wrap(ctx.Err(), 0)
+			}
+		}
+	}
+
+	limiter.mutex.Lock()
+	limiter.inFlight[resourceType]++
+	limiter.mutex.Unlock()
+
+	return func() {
+		limiter.mutex.Lock()
+		if limiter.inFlight[resourceType]--; limiter.inFlight[resourceType] <= 0 {
+			delete(limiter.inFlight, resourceType)
+		}
+		limiter.mutex.Unlock()
+		if slots, ok := limiter.slots[resourceType]; ok {
+			<-slots
+		}
+	}, nil
+}
+
+// Returns the number of resources of each type currently being processed.
+func (limiter *TypeLimiter) InFlight() map[string]int {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	inFlight := make(map[string]int, len(limiter.inFlight))
+	for resourceType, count := range limiter.inFlight {
+		inFlight[resourceType] = count
+	}
+	return inFlight
+}
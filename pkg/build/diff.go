@@ -0,0 +1,146 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Type of a single change in a diff.
+type ChangeOp string
+
+const (
+	// The resource does not exist, and needs to be created.
+	ChangeOpCreate ChangeOp = "create"
+
+	// A property is missing from the resource's current state.
+	ChangeOpAdd ChangeOp = "add"
+
+	// A property's current value differs from its desired value.
+	ChangeOpUpdate ChangeOp = "update"
+)
+
+// Represents a single difference between a resource's current state and its desired configuration.
+type Change struct {
+	Op      ChangeOp    `json:"op"`
+	Path    string      `json:"path"`
+	Current interface{} `json:"current,omitempty"`
+	Desired interface{} `json:"desired,omitempty"`
+}
+
+// Set of changes required to transition a resource from its current state to its desired state.
+type Diff []Change
+
+// Returns true if this diff contains no changes (ie. the resource is up-to-date).
+func (diff Diff) Empty() bool {
+	return len(diff) == 0
+}
+
+// Computes the diff between the given current state and desired configuration. A nil current state means the resource
+// does not exist, and yields a single "create" change. Otherwise, only properties present in the desired configuration
+// are compared; properties found only in the current state (eg. generated IDs or timestamps) are ignored, so a nil
+// desired configuration (ie. one without properties) yields an empty diff.
+func ComputeDiff(current interface{}, desired interface{}) Diff {
+	if current == nil {
+		return Diff{{Op: ChangeOpCreate, Path: "", Desired: desired}}
+	}
+	diff := make(Diff, 0)
+	if desired == nil {
+		return diff
+	}
+	compareValues(&diff, "", current, desired)
+	return diff
+}
+
+func compareValues(diff *Diff, path string, current interface{}, desired interface{}) {
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	currentMap, currentIsMap := current.(map[string]interface{})
+	if desiredIsMap && currentIsMap {
+		keys := make([]string, 0, len(desiredMap))
+		for key := range desiredMap {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			childPath := path + "/" + escapePointerToken(key)
+			if currentValue, ok := currentMap[key]; ok {
+				compareValues(diff, childPath, currentValue, desiredMap[key])
+			} else {
+				*diff = append(*diff, Change{Op: ChangeOpAdd, Path: childPath, Desired: desiredMap[key]})
+			}
+		}
+	} else if !valuesEqual(current, desired) {
+		*diff = append(*diff, Change{Op: ChangeOpUpdate, Path: path, Current: current, Desired: desired})
+	}
+}
+
+// Compares two JSON values, treating numbers as equal if they represent the same numeric value (eg. "1" and "1.0").
+func valuesEqual(a interface{}, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		if b, ok := b.(json.Number); ok {
+			af, aErr := a.Float64()
+			bf, bErr := b.Float64()
+			if aErr == nil && bErr == nil {
+				return af == bf
+			}
+			return a == b
+		}
+		return false
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			if other, ok := b[key]; !ok || !valuesEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !valuesEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// Escapes a single JSON pointer reference token, as defined in RFC 6901.
+func escapePointerToken(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+// Returns a human-friendly representation of this change.
+func (change Change) String() string {
+	path := change.Path
+	if path == "" {
+		path = "/"
+	}
+	switch change.Op {
+	case ChangeOpCreate:
+		return "create resource"
+	case ChangeOpAdd:
+		return fmt.Sprintf("add %s: %s", path, formatValue(change.Desired))
+	default:
+		return fmt.Sprintf("update %s: %s => %s", path, formatValue(change.Current), formatValue(change.Desired))
+	}
+}
+
+func formatValue(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(b)
+}
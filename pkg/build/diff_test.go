@@ -0,0 +1,85 @@
+package build_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/gitzup/agent/pkg/build"
+)
+
+// Decodes the given JSON the way resource states & configurations are decoded (ie. with numbers as json.Number).
+func decodeJSON(t *testing.T, s string) interface{} {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(s)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		t.Fatal(err)
+	}
+	return value
+}
+
+func TestComputeDiffOfMissingResource(t *testing.T) {
+	desired := decodeJSON(t, `{"port": 8080}`)
+	diff := build.ComputeDiff(nil, desired)
+	if len(diff) != 1 || diff[0].Op != build.ChangeOpCreate || !reflect.DeepEqual(diff[0].Desired, desired) {
+		t.Errorf("expected a single create change, got %v", diff)
+	}
+}
+
+func TestComputeDiffOfUpToDateResource(t *testing.T) {
+	current := decodeJSON(t, `{"port": 8080, "tags": ["a", "b"], "nested": {"ratio": 1}, "id": "generated"}`)
+	desired := decodeJSON(t, `{"port": 8080.0, "tags": ["a", "b"], "nested": {"ratio": 1.0}}`)
+	if diff := build.ComputeDiff(current, desired); !diff.Empty() {
+		t.Errorf("expected an empty diff, got %v", diff)
+	}
+}
+
+func TestComputeDiffWithNilDesiredConfig(t *testing.T) {
+	diff := build.ComputeDiff(decodeJSON(t, `{"port": 8080}`), nil)
+	if diff == nil || !diff.Empty() {
+		t.Errorf("expected an empty diff, got %#v", diff)
+	}
+}
+
+func TestComputeDiffOfChangedResource(t *testing.T) {
+	current := decodeJSON(t, `{"port": 8080, "tags": ["a"], "nested": {"name": "old"}, "a/b": 1}`)
+	desired := decodeJSON(t, `{"port": 9090, "tags": ["a", "b"], "nested": {"name": "new", "size": 3}, "a/b": 1, "host": "web"}`)
+	expected := build.Diff{
+		{Op: build.ChangeOpAdd, Path: "/host", Desired: "web"},
+		{Op: build.ChangeOpUpdate, Path: "/nested/name", Current: "old", Desired: "new"},
+		{Op: build.ChangeOpAdd, Path: "/nested/size", Desired: json.Number("3")},
+		{Op: build.ChangeOpUpdate, Path: "/port", Current: json.Number("8080"), Desired: json.Number("9090")},
+		{
+			Op:      build.ChangeOpUpdate,
+			Path:    "/tags",
+			Current: []interface{}{"a"},
+			Desired: []interface{}{"a", "b"},
+		},
+	}
+	if diff := build.ComputeDiff(current, desired); !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected %v, got %v", expected, diff)
+	}
+}
+
+func TestComputeDiffEscapesPaths(t *testing.T) {
+	diff := build.ComputeDiff(decodeJSON(t, `{}`), decodeJSON(t, `{"a/b~c": true}`))
+	if len(diff) != 1 || diff[0].Path != "/a~1b~0c" {
+		t.Errorf("expected path to be escaped, got %v", diff)
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	tests := map[string]build.Change{
+		"create resource":      {Op: build.ChangeOpCreate},
+		`add /host: "web"`:     {Op: build.ChangeOpAdd, Path: "/host", Desired: "web"},
+		"update /port: 1 => 2": {Op: build.ChangeOpUpdate, Path: "/port", Current: json.Number("1"), Desired: json.Number("2")},
+		`update /: 1 => "a"`:   {Op: build.ChangeOpUpdate, Current: json.Number("1"), Desired: "a"},
+	}
+	for expected, change := range tests {
+		if s := change.String(); s != expected {
+			t.Errorf("expected '%s', got '%s'", expected, s)
+		}
+	}
+}
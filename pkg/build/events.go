@@ -0,0 +1,142 @@
+package build
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/gitzup/agent/pkg/assets"
+)
+
+// Prefix of stdout lines through which actions report events (see the "event.json" schema), eg.:
+//
+//	::gitzup:: {"type": "progress", "percent": 40, "message": "Creating instances"}
+const EventPrefix = "::gitzup::"
+
+// Types of events reported by actions.
+const (
+	EventProgress = "progress"
+	EventWarning  = "warning"
+	EventOutput   = "output"
+	EventStep     = "step"
+)
+
+// An event reported by an action while it runs.
+type Event struct {
+	Action  string      `json:"action"`
+	Time    time.Time   `json:"time"`
+	Type    string      `json:"type"`
+	Percent *float64    `json:"percent,omitempty"`
+	Name    string      `json:"name,omitempty"`
+	Value   interface{} `json:"value,omitempty"`
+	Status  string      `json:"status,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// Parses the given output line as an event of the given action. Returns false if the line is not an event line.
+// Event lines that are not valid events are logged and ignored.
+func parseEvent(ctx context.Context, action string, line string) (*Event, bool) {
+	if !strings.HasPrefix(line, EventPrefix) {
+		return nil, false
+	}
+	event := &Event{Action: action, Time: time.Now()}
+	payload := strings.TrimSpace(strings.TrimPrefix(line, EventPrefix))
+	if err := assets.GetEventSchema().ParseAndValidate(event, []byte(payload)); err != nil {
+		From(ctx).WithError(err).Warnf("Ignoring illegal event: %s", payload)
+		return nil, true
+	}
+	event.Action, event.Time = action, time.Now()
+	return event, true
+}
+
+// Logs the given event, with its details as structured fields.
+func logEvent(ctx context.Context, event *Event) {
+	logger := From(ctx).WithField("event", event.Type)
+	if event.Percent != nil {
+		logger = logger.WithField("percent", *event.Percent)
+	}
+	if event.Name != "" {
+		logger = logger.WithField("name", event.Name)
+	}
+	if event.Value != nil {
+		logger = logger.WithField("value", event.Value)
+	}
+	if event.Status != "" {
+		logger = logger.WithField("status", event.Status)
+	}
+	switch {
+	case event.Type == EventWarning || event.Type == EventStep && event.Status == "failed":
+		logger.Warn(event.Message)
+	default:
+		logger.Info(event.Message)
+	}
+}
+
+// Splits an action's stdout into lines, handling event lines and passing all other lines on to the given writer.
+type eventWriter struct {
+	ctx     context.Context
+	action  string
+	next    io.Writer
+	handler func(event *Event)
+	partial []byte
+}
+
+func (writer *eventWriter) Write(p []byte) (int, error) {
+	writer.partial = append(writer.partial, p...)
+	for {
+		newline := strings.IndexByte(string(writer.partial), '\n')
+		if newline < 0 {
+			break
+		}
+		line := writer.partial[:newline+1]
+		writer.partial = writer.partial[newline+1:]
+		if err := writer.handle(line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+func (writer *eventWriter) handle(line []byte) error {
+	if event, ok := parseEvent(writer.ctx, writer.action, strings.TrimRight(string(line), "\r\n")); ok {
+		if event != nil {
+			logEvent(writer.ctx, event)
+			writer.handler(event)
+		}
+		return nil
+	}
+	_, err := writer.next.Write(line)
+	return err
+}
+
+// Handles the incomplete last line (if any).
+func (writer *eventWriter) flush() error {
+	if len(writer.partial) == 0 {
+		return nil
+	}
+	line := writer.partial
+	writer.partial = nil
+	return writer.handle(line)
+}
+
+// Events reported by an action, in order.
+type eventLog struct {
+	mutex  sync.Mutex
+	events []Event
+}
+
+func (log *eventLog) add(event *Event) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	log.events = append(log.events, *event)
+}
+
+// Returns the events reported so far.
+func (log *eventLog) Events() []Event {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return append([]Event(nil), log.events...)
+}
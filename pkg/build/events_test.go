@@ -0,0 +1,84 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestParseEvent(t *testing.T) {
+	event, ok := parseEvent(context.Background(), "apply", `::gitzup:: {"type": "progress", "percent": 40, "message": "Creating"}`)
+	if !ok || event == nil {
+		t.Fatalf("expected a progress event, got %+v (%v)", event, ok)
+	}
+	if event.Action != "apply" || event.Type != EventProgress || event.Message != "Creating" || event.Time.IsZero() {
+		t.Errorf("unexpected event: %+v", event)
+	} else if event.Percent == nil || *event.Percent != 40 {
+		t.Errorf("expected progress of 40%%, got %v", event.Percent)
+	}
+
+	event, ok = parseEvent(context.Background(), "apply", `::gitzup::{"type": "output", "name": "id", "value": "i-123"}`)
+	if !ok || event == nil || event.Type != EventOutput || event.Name != "id" || event.Value != "i-123" {
+		t.Errorf("expected an output event, got %+v (%v)", event, ok)
+	}
+}
+
+func TestParseEventIgnoresIllegalEvents(t *testing.T) {
+	illegal := []string{
+		`::gitzup:: not json`,
+		`::gitzup:: {"type": "unknown"}`,
+		`::gitzup:: {"type": "progress", "percent": 140}`,
+		`::gitzup:: {"type": "warning"}`,
+		`::gitzup:: {"type": "step", "name": "deploy", "status": "paused"}`,
+		`::gitzup:: {"type": "warning", "message": "careful", "action": "spoofed"}`,
+	}
+	for _, line := range illegal {
+		if event, ok := parseEvent(context.Background(), "apply", line); !ok || event != nil {
+			t.Errorf("expected illegal event line to be consumed & ignored: %s (got %+v, %v)", line, event, ok)
+		}
+	}
+	for _, line := range []string{"plain output", " ::gitzup:: {}", "::GITZUP:: {}"} {
+		if _, ok := parseEvent(context.Background(), "apply", line); ok {
+			t.Errorf("expected line not to be an event line: %s", line)
+		}
+	}
+}
+
+func TestEventWriter(t *testing.T) {
+	var output bytes.Buffer
+	var events eventLog
+	writer := &eventWriter{ctx: context.Background(), action: "apply", next: &output, handler: events.add}
+
+	// lines may be split across writes, and the last line may be incomplete
+	chunks := []string{
+		"first line\n::gitzup:: {\"type\": \"step\", \"na",
+		"me\": \"deploy\", \"status\": \"started\"}\r\nsecond",
+		" line\n::gitzup:: {\"type\": \"bogus\"}\n",
+		"::gitzup:: {\"type\": \"warning\", \"message\": \"last\"}",
+	}
+	for _, chunk := range chunks {
+		if n, err := writer.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("expected %d bytes to be written, got %d (%v)", len(chunk), n, err)
+		}
+	}
+	if len(events.Events()) != 1 {
+		t.Errorf("expected incomplete last line to be buffered, got events %+v", events.Events())
+	}
+	if err := writer.flush(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if expected := "first line\nsecond line\n"; output.String() != expected {
+		t.Errorf("expected output %q, got %q", expected, output.String())
+	}
+	logged := events.Events()
+	if len(logged) != 2 {
+		t.Fatalf("expected 2 events, got %+v", logged)
+	}
+	if logged[0].Type != EventStep || logged[0].Name != "deploy" || logged[0].Status != "started" {
+		t.Errorf("unexpected first event: %+v", logged[0])
+	}
+	if logged[1].Type != EventWarning || logged[1].Message != "last" || logged[1].Action != "apply" {
+		t.Errorf("unexpected second event: %+v", logged[1])
+	}
+}
@@ -0,0 +1,410 @@
+package build
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+// Scripted behavior of a fake action: receives the action's container specification (including its input), and returns
+// the response the action writes to its result file, or an error to simulate a failing container. Handlers may simulate
+// container output by writing to the specification's Stdout & Stderr writers (if not nil).
+type FakeActionHandler func(ctx context.Context, spec *ContainerSpec) (interface{}, error)
+
+// Error which, when returned by a fake action handler, simulates the action's container being killed for exceeding its
+// memory limit.
+var FakeOOMKill = errors.New("fake out-of-memory kill")
+
+// A single container run recorded by the fake runtime.
+type FakeRun struct {
+	Action string
+	Spec   ContainerSpec
+}
+
+// In-memory container runtime for testing request orchestration without a Docker daemon. Containers are never actually
+// run; instead, each run is answered by the handler scripted for its image & action (as identified by the
+// "GITZUP_ACTION_NAME" environment variable of the container). Runs of unscripted image & action pairs fail.
+type FakeRuntime struct {
+	mutex      sync.Mutex
+	handlers   map[string]FakeActionHandler
+	artifacts  map[string]map[string][]byte
+	containers map[string]*fakeContainer
+	images     map[string]string
+	networks   map[string]*NetworkInfo
+	pulled     []string
+	runs       []FakeRun
+	nextID     int
+}
+
+type fakeContainer struct {
+	info  ContainerInfo
+	files map[string][]byte
+}
+
+// Creates a new fake runtime with no scripted actions.
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		handlers:   make(map[string]FakeActionHandler),
+		artifacts:  make(map[string]map[string][]byte),
+		containers: make(map[string]*fakeContainer),
+		images:     make(map[string]string),
+		networks:   make(map[string]*NetworkInfo),
+	}
+}
+
+func fakeActionKey(image string, action string) string {
+	return image + "#" + action
+}
+
+// Scripts the given action of the given image to be answered by the given handler.
+func (rt *FakeRuntime) Handle(image string, action string, handler FakeActionHandler) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	rt.handlers[fakeActionKey(image, action)] = handler
+}
+
+// Scripts the given action of the given image to always respond with the given response.
+func (rt *FakeRuntime) Respond(image string, action string, response interface{}) {
+	rt.Handle(image, action, func(ctx context.Context, spec *ContainerSpec) (interface{}, error) {
+		return response, nil
+	})
+}
+
+// Scripts the given action of the given image to always fail with the given error.
+func (rt *FakeRuntime) Fail(image string, action string, err error) {
+	rt.Handle(image, action, func(ctx context.Context, spec *ContainerSpec) (interface{}, error) {
+		return nil, err
+	})
+}
+
+// Scripts the given action of the given image to hand back the given artifacts (keyed by their path relative to the
+// artifacts directory) when it succeeds.
+func (rt *FakeRuntime) Artifacts(image string, action string, files map[string][]byte) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	rt.artifacts[fakeActionKey(image, action)] = files
+}
+
+// Marks the given image as present locally, with the given digest.
+func (rt *FakeRuntime) AddImage(image string, digest string) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	rt.images[image] = digest
+}
+
+// Returns the images pulled so far, in order.
+func (rt *FakeRuntime) Pulled() []string {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	return append([]string(nil), rt.pulled...)
+}
+
+// Returns the names of the networks currently present, keyed by their IDs.
+func (rt *FakeRuntime) Networks() map[string]string {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	networks := make(map[string]string, len(rt.networks))
+	for id, network := range rt.networks {
+		networks[id] = network.Name
+	}
+	return networks
+}
+
+// Registers an existing container which is not running any action (eg. one left behind by a crashed agent). An ID is
+// assigned to the container if it has none.
+func (rt *FakeRuntime) AddContainer(info ContainerInfo) string {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	if info.ID == "" {
+		rt.nextID++
+		info.ID = fmt.Sprintf("fake-%d", rt.nextID)
+	}
+	rt.containers[info.ID] = &fakeContainer{info: info, files: make(map[string][]byte)}
+	return info.ID
+}
+
+// Registers an existing network (eg. one left behind by a crashed agent). An ID is assigned to the network if it has
+// none.
+func (rt *FakeRuntime) AddNetwork(info NetworkInfo) string {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	if info.ID == "" {
+		rt.nextID++
+		info.ID = fmt.Sprintf("fake-network-%d", rt.nextID)
+	}
+	rt.networks[info.ID] = &info
+	return info.ID
+}
+
+// Returns the containers run so far, in order.
+func (rt *FakeRuntime) Runs() []FakeRun {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	return append([]FakeRun(nil), rt.runs...)
+}
+
+func (rt *FakeRuntime) Pull(ctx context.Context, image string, policy PullPolicy) (string, error) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	_, present := rt.images[image]
+	switch EffectivePullPolicy(policy, image) {
+	case PullAlways:
+	case PullIfNotPresent:
+		if present {
+			return rt.images[image], nil
+		}
+	case PullNever:
+		if !present {
+			return "", errors.Errorf("image '%s' is not present, and pull policy is '%s'", image, PullNever)
+		}
+		return rt.images[image], nil
+	default:
+		return "", errors.Errorf("unknown pull policy '%s'", policy)
+	}
+
+	// "pull" the image, giving it a digest derived from its reference
+	rt.pulled = append(rt.pulled, image)
+	if at := strings.Index(image, "@"); at >= 0 {
+		rt.images[image] = image[at+1:]
+	} else {
+		rt.images[image] = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(image)))
+	}
+	return rt.images[image], nil
+}
+
+func (rt *FakeRuntime) Run(ctx context.Context, spec *ContainerSpec, preExitHandler ContainerHandler, postExitHandler ContainerHandler) error {
+	if ctx.Err() != nil {
+		return errors.WrapPrefix(ctx.Err(), "container stopped", 0)
+	}
+
+	// find the action's handler, and register the container
+	var action string
+	for _, variable := range spec.Env {
+		if strings.HasPrefix(variable, "GITZUP_ACTION_NAME=") {
+			action = strings.TrimPrefix(variable, "GITZUP_ACTION_NAME=")
+		}
+	}
+	rt.mutex.Lock()
+	rt.nextID++
+	c := &fakeContainer{
+		info: ContainerInfo{
+			ID:      fmt.Sprintf("fake-%d", rt.nextID),
+			Name:    spec.Name,
+			Image:   spec.Image,
+			Running: true,
+			Labels:  spec.Labels,
+			Created: time.Now(),
+		},
+		files: make(map[string][]byte),
+	}
+	for id, existing := range rt.containers {
+		if existing.info.Name == spec.Name && IsStaleContainer(existing.info, spec) {
+			delete(rt.containers, id)
+		} else if existing.info.Name == spec.Name {
+			rt.mutex.Unlock()
+			return errors.Errorf("failed creating container: name '%s' is already in use", spec.Name)
+		}
+	}
+	rt.containers[c.info.ID] = c
+	rt.runs = append(rt.runs, FakeRun{Action: action, Spec: *spec})
+	handler, ok := rt.handlers[fakeActionKey(spec.Image, action)]
+	artifacts := rt.artifacts[fakeActionKey(spec.Image, action)]
+	rt.mutex.Unlock()
+	defer func() {
+		rt.mutex.Lock()
+		defer rt.mutex.Unlock()
+		delete(rt.containers, c.info.ID)
+	}()
+
+	if !ok {
+		return errors.Errorf("no fake response scripted for action '%s' of image '%s'", action, spec.Image)
+	}
+
+	// pass the input through JSON, as a real container would receive it
+	if spec.Input != nil {
+		b, err := json.Marshal(spec.Input)
+		if err != nil {
+			return errors.WrapPrefix(err, "failed serializing request input to JSON", 0)
+		}
+		var input interface{}
+		if err := json.Unmarshal(b, &input); err != nil {
+			return errors.WrapPrefix(err, "failed serializing request input to JSON", 0)
+		}
+		specCopy := *spec
+		specCopy.Input = input
+		spec = &specCopy
+	}
+
+	if preExitHandler != nil {
+		if err := preExitHandler(ctx, c.info.ID); err != nil {
+			return errors.WrapPrefix(err, "failed invoking pre-exit callback on container", 0)
+		}
+	}
+
+	// "run" the container
+	response, err := handler(ctx, spec)
+	rt.mutex.Lock()
+	c.info.Running = false
+	if err == FakeOOMKill {
+		c.info.OOMKilled = true
+		c.info.ExitCode = 137
+	} else if err != nil {
+		c.info.ExitCode = 1
+	}
+	rt.mutex.Unlock()
+	if ctx.Err() != nil {
+		return errors.WrapPrefix(ctx.Err(), "container stopped", 0)
+	} else if err == FakeOOMKill {
+		return OOMKilledError(spec.Limits.Memory)
+	} else if err != nil {
+		return errors.WrapPrefix(err, "container terminated with exit-code 1", 0)
+	}
+	if response != nil {
+		b, err := json.Marshal(response)
+		if err != nil {
+			return errors.WrapPrefix(err, "failed serializing fake response to JSON", 0)
+		}
+		rt.mutex.Lock()
+		c.files[actionResultPath] = b
+		rt.mutex.Unlock()
+	}
+	rt.mutex.Lock()
+	for name, content := range artifacts {
+		c.files[actionArtifactsPath+"/"+name] = content
+	}
+	rt.mutex.Unlock()
+
+	if postExitHandler != nil {
+		if err := postExitHandler(ctx, c.info.ID); err != nil {
+			return errors.WrapPrefix(err, "failed invoking post-exit callback on container", 0)
+		}
+	}
+	return nil
+}
+
+func (rt *FakeRuntime) CopyFrom(ctx context.Context, containerID string, path string) ([]byte, error) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	c, ok := rt.containers[containerID]
+	if !ok {
+		return nil, errors.Errorf("no such container: %s", containerID)
+	}
+	b, ok := c.files[path]
+	if !ok {
+		return nil, errors.Errorf("could not find '%s' in container", path)
+	}
+	return b, nil
+}
+
+func (rt *FakeRuntime) CopyArchiveFrom(ctx context.Context, containerID string, path string) (io.ReadCloser, error) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	c, ok := rt.containers[containerID]
+	if !ok {
+		return nil, errors.Errorf("no such container: %s", containerID)
+	}
+
+	// archive entries are rooted at the path's base name, as Docker does
+	base := path[strings.LastIndex(path, "/")+1:]
+	var buffer bytes.Buffer
+	tw := tar.NewWriter(&buffer)
+	found := false
+	for name, content := range c.files {
+		if name != path && !strings.HasPrefix(name, path+"/") {
+			continue
+		}
+		found = true
+		header := &tar.Header{Name: base + strings.TrimPrefix(name, path), Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, errors.WrapPrefix(err, "failed archiving fake container files", 0)
+		} else if _, err := tw.Write(content); err != nil {
+			return nil, errors.WrapPrefix(err, "failed archiving fake container files", 0)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, errors.WrapPrefix(err, "failed archiving fake container files", 0)
+	} else if !found {
+		return nil, nil
+	}
+	return ioutil.NopCloser(&buffer), nil
+}
+
+func (rt *FakeRuntime) Inspect(ctx context.Context, containerID string) (*ContainerInfo, error) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	c, ok := rt.containers[containerID]
+	if !ok {
+		return nil, errors.Errorf("no such container: %s", containerID)
+	}
+	info := c.info
+	return &info, nil
+}
+
+func (rt *FakeRuntime) ListContainers(ctx context.Context) ([]ContainerInfo, error) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	var containers []ContainerInfo
+	for _, c := range rt.containers {
+		if c.info.Labels[LabelManaged] == "true" {
+			containers = append(containers, c.info)
+		}
+	}
+	return containers, nil
+}
+
+func (rt *FakeRuntime) RemoveContainer(ctx context.Context, containerID string) error {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	if _, ok := rt.containers[containerID]; !ok {
+		return errors.Errorf("no such container: %s", containerID)
+	}
+	delete(rt.containers, containerID)
+	return nil
+}
+
+func (rt *FakeRuntime) CreateNetwork(ctx context.Context, name string, labels map[string]string) (string, error) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	for _, existing := range rt.networks {
+		if existing.Name == name {
+			return "", errors.Errorf("network with name %s already exists", name)
+		}
+	}
+	rt.nextID++
+	id := fmt.Sprintf("fake-network-%d", rt.nextID)
+	rt.networks[id] = &NetworkInfo{ID: id, Name: name, Labels: labels, Created: time.Now()}
+	return id, nil
+}
+
+func (rt *FakeRuntime) ListNetworks(ctx context.Context) ([]NetworkInfo, error) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	var networks []NetworkInfo
+	for _, network := range rt.networks {
+		if network.Labels[LabelManaged] == "true" {
+			networks = append(networks, *network)
+		}
+	}
+	return networks, nil
+}
+
+func (rt *FakeRuntime) RemoveNetwork(ctx context.Context, networkID string) error {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	if _, ok := rt.networks[networkID]; !ok {
+		return errors.Errorf("no such network: %s", networkID)
+	}
+	delete(rt.networks, networkID)
+	return nil
+}
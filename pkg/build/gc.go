@@ -0,0 +1,152 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/go-errors/errors"
+)
+
+// Options controlling garbage collection of stale build request leftovers.
+type GCOptions struct {
+	// Container runtime to collect containers & networks from (required).
+	Runtime Runtime
+
+	// Directory containing the build request workspaces to collect; if empty, workspaces are not collected.
+	WorkspacePath string
+
+	// Minimum age of containers, networks & workspaces to collect.
+	MaxAge time.Duration
+
+	// If not empty, only containers, networks & workspaces created by this agent instance are collected (see
+	// Options.Instance).
+	Instance string
+}
+
+// Summary of a garbage collection run.
+type GCResult struct {
+	Containers []string
+	Networks   []string
+	Workspaces []string
+}
+
+// Removes containers, networks & workspaces left behind by build requests (eg. if the agent crashed mid-build) which
+// are older than the configured maximum age. Leftovers of build requests still being processed by this process are
+// never removed. Collection continues past individual failures; the first failure (if any) is returned along with the
+// summary of what was removed.
+func CollectGarbage(ctx context.Context, options GCOptions) (*GCResult, error) {
+	if options.Runtime == nil {
+		return nil, errors.New("container runtime is required")
+	}
+	result := &GCResult{}
+	threshold := time.Now().Add(-options.MaxAge)
+	var firstErr error
+	fail := func(err error) {
+		From(ctx).WithError(err).Warn("Garbage collection failure")
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// containers go first, since networks cannot be removed while containers are attached to them
+	containers, err := options.Runtime.ListContainers(ctx)
+	if err != nil {
+		fail(err)
+	}
+	for _, container := range containers {
+		if isCollectable(container.Labels, container.Created, threshold, options.Instance) {
+			From(ctx).Infof("Removing stale container '%s'", container.Name)
+			if err := options.Runtime.RemoveContainer(ctx, container.ID); err != nil {
+				fail(err)
+			} else {
+				result.Containers = append(result.Containers, container.Name)
+			}
+		}
+	}
+
+	networks, err := options.Runtime.ListNetworks(ctx)
+	if err != nil {
+		fail(err)
+	}
+	for _, network := range networks {
+		if isCollectable(network.Labels, network.Created, threshold, options.Instance) {
+			From(ctx).Infof("Removing stale network '%s'", network.Name)
+			if err := options.Runtime.RemoveNetwork(ctx, network.ID); err != nil {
+				fail(err)
+			} else {
+				result.Networks = append(result.Networks, network.Name)
+			}
+		}
+	}
+
+	if options.WorkspacePath != "" {
+		workspaces, err := staleWorkspaces(options.WorkspacePath, threshold, options.Instance)
+		if err != nil {
+			fail(err)
+		}
+		for _, workspace := range workspaces {
+			From(ctx).Infof("Removing stale workspace '%s'", workspace)
+			if err := os.RemoveAll(workspace); err != nil {
+				fail(errors.WrapPrefix(err, fmt.Sprintf("failed removing workspace '%s'", workspace), 0))
+			} else {
+				result.Workspaces = append(result.Workspaces, workspace)
+			}
+		}
+	}
+
+	return result, firstErr
+}
+
+// Returns whether a container or network with the given labels & creation time should be collected.
+func isCollectable(labels map[string]string, created time.Time, threshold time.Time, instance string) bool {
+	if labels[LabelManaged] != "true" || isActive(labels[LabelRequest]) {
+		return false
+	} else if instance != "" && labels[LabelAgent] != instance {
+		return false
+	}
+	return created.Before(threshold)
+}
+
+// Returns the build request workspaces in the given directory created before the given threshold (and by the given
+// agent instance, if not empty). Only directories containing the workspace marker file are considered, so unrelated
+// directories are never touched.
+func staleWorkspaces(workspacePath string, threshold time.Time, instance string) ([]string, error) {
+	entries, err := ioutil.ReadDir(workspacePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed listing workspaces in '%s'", workspacePath), 0)
+	}
+	var workspaces []string
+	for _, entry := range entries {
+		if !entry.IsDir() || isActive(entry.Name()) {
+			continue
+		}
+		markerPath := filepath.Join(workspacePath, entry.Name(), workspaceMarkerFile)
+		marker, err := os.Stat(markerPath)
+		if err != nil {
+			continue
+		} else if instance != "" && readWorkspaceMarker(markerPath).Instance != instance {
+			continue
+		}
+		if marker.ModTime().Before(threshold) {
+			workspaces = append(workspaces, filepath.Join(workspacePath, entry.Name()))
+		}
+	}
+	return workspaces, nil
+}
+
+// Reads the given workspace marker file. Unreadable markers yield an empty marker, matching no agent instance.
+func readWorkspaceMarker(path string) workspaceMarker {
+	var marker workspaceMarker
+	if b, err := ioutil.ReadFile(path); err == nil {
+		_ = json.Unmarshal(b, &marker)
+	}
+	return marker
+}
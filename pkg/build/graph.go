@@ -0,0 +1,75 @@
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// Sorts the given resources topologically, such that each resource appears after all the resources it depends on.
+// Fails if a resource depends on an unknown resource, or if the dependencies form a cycle (the error will contain the
+// cycle's path).
+//
+// Resources are visited by name, so the resulting order is stable for a given set of resources.
+func sortResources(resources map[string]*resourceImpl) ([]*resourceImpl, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// verify all dependencies exist before walking the graph
+	for _, name := range names {
+		for _, dependency := range resources[name].dependsOn {
+			if _, ok := resources[dependency]; !ok {
+				return nil, errors.Errorf("resource '%s' depends on unknown resource '%s'", name, dependency)
+			}
+		}
+	}
+
+	sorted := make([]*resourceImpl, 0, len(resources))
+	states := make(map[string]int, len(resources))
+	var stack []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch states[name] {
+		case visited:
+			return nil
+		case visiting:
+			// find where the cycle begins in the current path, and report the path from there
+			for i, n := range stack {
+				if n == name {
+					cycle := append(append([]string{}, stack[i:]...), name)
+					return errors.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> "))
+				}
+			}
+			panic(fmt.Sprintf("resource '%s' is being visited but not in stack", name))
+		}
+
+		states[name] = visiting
+		stack = append(stack, name)
+		for _, dependency := range resources[name].dependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[name] = visited
+		sorted = append(sorted, resources[name])
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,86 @@
+package build
+
+import (
+	"sync"
+	"time"
+)
+
+// Labels placed on all containers & networks created for build requests, so they can be identified (and garbage
+// collected) even if the agent that created them crashed.
+const (
+	// Marks a container or network as created by a Gitzup agent (value is always "true").
+	LabelManaged = "com.gitzup.managed"
+
+	// ID of the build request the container or network was created for.
+	LabelRequest = "com.gitzup.request"
+
+	// Name of the resource the container was created for.
+	LabelResource = "com.gitzup.resource"
+
+	// Name of the action the container was created for.
+	LabelAction = "com.gitzup.action"
+
+	// Identifier of the agent instance that created the container or network (see Options.Instance).
+	LabelAgent = "com.gitzup.agent"
+
+	// Creation time of the container or network, in RFC 3339 format.
+	LabelCreated = "com.gitzup.created"
+)
+
+// Name of the marker file placed in each build request workspace, identifying it as such for garbage collection.
+const workspaceMarkerFile = ".gitzup-request"
+
+// Contents of a build request workspace's marker file.
+type workspaceMarker struct {
+	RequestId string `json:"requestId"`
+	Instance  string `json:"instance,omitempty"`
+}
+
+// Build requests currently being processed by this process; their containers, networks & workspaces are never garbage
+// collected.
+var activeRequests = struct {
+	sync.Mutex
+	ids map[string]int
+}{ids: make(map[string]int)}
+
+// Marks the given build request as active, returning a function which marks it as inactive again.
+func markActive(requestID string) func() {
+	activeRequests.Lock()
+	defer activeRequests.Unlock()
+	activeRequests.ids[requestID]++
+	return func() {
+		activeRequests.Lock()
+		defer activeRequests.Unlock()
+		if activeRequests.ids[requestID]--; activeRequests.ids[requestID] <= 0 {
+			delete(activeRequests.ids, requestID)
+		}
+	}
+}
+
+// Returns whether the given build request is currently being processed by this process.
+func isActive(requestID string) bool {
+	activeRequests.Lock()
+	defer activeRequests.Unlock()
+	return activeRequests.ids[requestID] > 0
+}
+
+// Returns whether the given existing container, which has the name of the given container specification, is stale and
+// may be replaced: it must have been created by a Gitzup agent, and either have exited, or have been created by the
+// agent instance of the specification (which never runs the same action twice at once, so the container must have been
+// left behind by a previous incarnation of it). Other containers may still be in use, and must not be replaced.
+func IsStaleContainer(existing ContainerInfo, spec *ContainerSpec) bool {
+	if existing.Labels[LabelManaged] != "true" {
+		return false
+	}
+	return !existing.Running || existing.Labels[LabelAgent] == spec.Labels[LabelAgent]
+}
+
+// Returns the labels to place on containers & networks created for the given build request.
+func (req *requestImpl) labels() map[string]string {
+	return map[string]string{
+		LabelManaged: "true",
+		LabelRequest: req.Id(),
+		LabelAgent:   req.options.Instance,
+		LabelCreated: time.Now().UTC().Format(time.RFC3339),
+	}
+}
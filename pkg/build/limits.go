@@ -0,0 +1,148 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// Resource limits of an action container. Zero values mean no limit.
+type Limits struct {
+	// Number of CPUs (may be fractional, eg. 0.5).
+	CPUs float64
+
+	// Memory, in bytes.
+	Memory int64
+
+	// Maximum number of processes (and threads).
+	PIDs int64
+}
+
+// Returns a copy of these limits, with any non-zero limit in the given overrides replacing the corresponding limit.
+func (limits Limits) Merge(overrides Limits) Limits {
+	if overrides.CPUs > 0 {
+		limits.CPUs = overrides.CPUs
+	}
+	if overrides.Memory > 0 {
+		limits.Memory = overrides.Memory
+	}
+	if overrides.PIDs > 0 {
+		limits.PIDs = overrides.PIDs
+	}
+	return limits
+}
+
+// Verifies that these limits do not exceed the given maximum limits, returning the limits to enforce: unlimited values
+// are replaced by their maximum (if any).
+func (limits Limits) Enforce(max Limits) (Limits, error) {
+	if max.CPUs > 0 {
+		if limits.CPUs > max.CPUs {
+			return limits, errors.Errorf("CPU limit %g exceeds the maximum of %g", limits.CPUs, max.CPUs)
+		} else if limits.CPUs == 0 {
+			limits.CPUs = max.CPUs
+		}
+	}
+	if max.Memory > 0 {
+		if limits.Memory > max.Memory {
+			return limits, errors.Errorf("memory limit %s exceeds the maximum of %s", FormatMemory(limits.Memory), FormatMemory(max.Memory))
+		} else if limits.Memory == 0 {
+			limits.Memory = max.Memory
+		}
+	}
+	if max.PIDs > 0 {
+		if limits.PIDs > max.PIDs {
+			return limits, errors.Errorf("PIDs limit %d exceeds the maximum of %d", limits.PIDs, max.PIDs)
+		} else if limits.PIDs == 0 {
+			limits.PIDs = max.PIDs
+		}
+	}
+	return limits, nil
+}
+
+// Parses limits from their JSON representation (see the "limits.json" schema). The given path is only used for error
+// messages.
+func parseLimits(path string, value interface{}) (Limits, error) {
+	var limits Limits
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return limits, nil
+	}
+	if cpus, ok := m["cpus"].(json.Number); ok {
+		value, err := cpus.Float64()
+		if err != nil {
+			return limits, errors.WrapPrefix(err, fmt.Sprintf("illegal CPU limit at '%s/cpus'", path), 0)
+		}
+		limits.CPUs = value
+	}
+	switch memory := m["memory"].(type) {
+	case json.Number:
+		value, err := memory.Int64()
+		if err != nil {
+			return limits, errors.WrapPrefix(err, fmt.Sprintf("illegal memory limit at '%s/memory'", path), 0)
+		}
+		limits.Memory = value
+	case string:
+		value, err := ParseMemory(memory)
+		if err != nil {
+			return limits, errors.WrapPrefix(err, fmt.Sprintf("illegal memory limit at '%s/memory'", path), 0)
+		} else if value == 0 {
+			return limits, errors.Errorf("illegal memory limit at '%s/memory': must be at least one byte", path)
+		}
+		limits.Memory = value
+	}
+	if pids, ok := m["pids"].(json.Number); ok {
+		value, err := pids.Int64()
+		if err != nil {
+			return limits, errors.WrapPrefix(err, fmt.Sprintf("illegal PIDs limit at '%s/pids'", path), 0)
+		}
+		limits.PIDs = value
+	}
+	return limits, nil
+}
+
+// Parses a memory amount, either in bytes (eg. "1048576") or with a binary unit suffix (eg. "512k", "256m", "1.5g").
+func ParseMemory(value string) (int64, error) {
+	units := map[string]float64{"": 1, "b": 1, "k": 1 << 10, "m": 1 << 20, "g": 1 << 30, "t": 1 << 40}
+	value = strings.ToLower(strings.TrimSpace(value))
+	number := strings.TrimRight(value, "bkmgt")
+	unit, ok := units[value[len(number):]]
+	if !ok {
+		return 0, errors.Errorf("invalid memory amount '%s'", value)
+	}
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil || amount < 0 || math.IsNaN(amount) || amount*unit >= math.MaxInt64 {
+		return 0, errors.Errorf("invalid memory amount '%s'", value)
+	}
+
+	// a non-zero amount must not round down to zero bytes, since zero means no limit
+	bytes := int64(amount * unit)
+	if bytes == 0 && amount > 0 {
+		return 0, errors.Errorf("invalid memory amount '%s' (less than one byte)", value)
+	}
+	return bytes, nil
+}
+
+// Formats the given amount of memory (in bytes) using the largest binary unit that represents it exactly.
+func FormatMemory(bytes int64) string {
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"g", 1 << 30}, {"m", 1 << 20}, {"k", 1 << 10}} {
+		if bytes >= unit.size && bytes%unit.size == 0 {
+			return fmt.Sprintf("%d%s", bytes/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%db", bytes)
+}
+
+// Returns the error reported when an action container is killed for exceeding the given memory limit.
+func OOMKilledError(memory int64) error {
+	if memory > 0 {
+		return errors.Errorf("container was killed for exceeding its memory limit (%s)", FormatMemory(memory))
+	}
+	return errors.New("container was killed for running out of memory")
+}
@@ -0,0 +1,110 @@
+package build
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLimitsMerge(t *testing.T) {
+	defaults := Limits{CPUs: 1, Memory: 1 << 30, PIDs: 100}
+	merged := defaults.Merge(Limits{CPUs: 0.5, PIDs: 50})
+	if expected := (Limits{CPUs: 0.5, Memory: 1 << 30, PIDs: 50}); merged != expected {
+		t.Errorf("expected %+v, got %+v", expected, merged)
+	}
+	if merged := defaults.Merge(Limits{}); merged != defaults {
+		t.Errorf("expected empty overrides to keep %+v, got %+v", defaults, merged)
+	}
+}
+
+func TestLimitsEnforce(t *testing.T) {
+	max := Limits{CPUs: 2, Memory: 1 << 30, PIDs: 100}
+	tests := []struct {
+		limits   Limits
+		expected Limits
+		fails    bool
+	}{
+		{limits: Limits{}, expected: max},
+		{limits: Limits{CPUs: 1, Memory: 1 << 20, PIDs: 10}, expected: Limits{CPUs: 1, Memory: 1 << 20, PIDs: 10}},
+		{limits: Limits{CPUs: 2, Memory: 1 << 30, PIDs: 100}, expected: max},
+		{limits: Limits{CPUs: 2.5}, fails: true},
+		{limits: Limits{Memory: 1<<30 + 1}, fails: true},
+		{limits: Limits{PIDs: 101}, fails: true},
+	}
+	for _, test := range tests {
+		enforced, err := test.limits.Enforce(max)
+		if test.fails && err == nil {
+			t.Errorf("expected limits %+v to exceed the maximum limits", test.limits)
+		} else if !test.fails && err != nil {
+			t.Errorf("unexpected error for limits %+v: %s", test.limits, err)
+		} else if !test.fails && enforced != test.expected {
+			t.Errorf("expected limits %+v to be enforced as %+v, got %+v", test.limits, test.expected, enforced)
+		}
+	}
+	if enforced, err := (Limits{CPUs: 8}).Enforce(Limits{}); err != nil || enforced != (Limits{CPUs: 8}) {
+		t.Errorf("expected no maximum limits to keep limits as-is, got %+v (%v)", enforced, err)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := parseLimits("/limits", map[string]interface{}{
+		"cpus":   json.Number("0.5"),
+		"memory": "1.5g",
+		"pids":   json.Number("64"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if expected := (Limits{CPUs: 0.5, Memory: 3 << 29, PIDs: 64}); limits != expected {
+		t.Errorf("expected %+v, got %+v", expected, limits)
+	}
+
+	limits, err = parseLimits("/limits", map[string]interface{}{"memory": json.Number("1048576")})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if limits.Memory != 1<<20 {
+		t.Errorf("expected memory limit of 1m, got %s", FormatMemory(limits.Memory))
+	}
+
+	if limits, err := parseLimits("/limits", nil); err != nil || limits != (Limits{}) {
+		t.Errorf("expected missing limits to mean no limits, got %+v (%v)", limits, err)
+	}
+	for _, memory := range []string{"0", "0.0001k", "12x"} {
+		if _, err := parseLimits("/limits", map[string]interface{}{"memory": memory}); err == nil {
+			t.Errorf("expected memory limit '%s' to be rejected", memory)
+		}
+	}
+}
+
+func TestParseMemory(t *testing.T) {
+	valid := map[string]int64{
+		"0":       0,
+		"1048576": 1 << 20,
+		"100b":    100,
+		"512k":    512 << 10,
+		"256M":    256 << 20,
+		"1.5g":    3 << 29,
+		"2t":      2 << 40,
+		" 64m ":   64 << 20,
+		"0.5k":    512,
+	}
+	for value, expected := range valid {
+		if bytes, err := ParseMemory(value); err != nil {
+			t.Errorf("unexpected error parsing '%s': %s", value, err)
+		} else if bytes != expected {
+			t.Errorf("expected '%s' to be %d bytes, got %d", value, expected, bytes)
+		}
+	}
+	for _, value := range []string{"", "k", "-1m", "1x", "1kk", "0.0001k", "0.1", "inf", "nan", "99999999999t"} {
+		if bytes, err := ParseMemory(value); err == nil {
+			t.Errorf("expected '%s' to be rejected, got %d bytes", value, bytes)
+		}
+	}
+}
+
+func TestFormatMemory(t *testing.T) {
+	tests := map[int64]string{0: "0b", 100: "100b", 1024: "1k", 1536: "1536b", 3 << 29: "1536m", 2 << 30: "2g"}
+	for bytes, expected := range tests {
+		if formatted := FormatMemory(bytes); formatted != expected {
+			t.Errorf("expected %d bytes to be formatted as '%s', got '%s'", bytes, expected, formatted)
+		}
+	}
+}
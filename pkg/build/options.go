@@ -0,0 +1,74 @@
+package build
+
+import "time"
+
+// Options controlling how a build request is processed.
+type Options struct {
+	// Container runtime to run resource actions with (required).
+	Runtime Runtime
+
+	// Maximum number of resources to process concurrently. Resources are only processed concurrently if they do not
+	// depend on each other (directly or indirectly). Values lower than 1 are treated as 1.
+	Parallelism int
+
+	// Limiter of the number of resources of each type processed concurrently, usually shared by all build requests of
+	// the agent; if nil, resource types are not limited.
+	TypeLimiter *TypeLimiter
+
+	// Whether to abort the build request as soon as any resource fails. If false, the build request will continue
+	// processing any resources that do not depend on the failed resource, and fail once all of them are done.
+	FailFast bool
+
+	// Identifier of the agent instance processing the build request (eg. its hostname); recorded on the containers &
+	// networks it creates, so leftovers of a crashed agent can be identified & garbage collected.
+	Instance string
+
+	// Project the build request belongs to. Resource state records are kept per project.
+	Project string
+
+	// Store for persisting resource state records across build requests; if nil, no state is persisted.
+	StateStore StateStore
+
+	// User (and optionally group) to run action containers as, in "<user>[:<group>]" format; if empty, the image's
+	// default user is used. Files written by actions to their workspace are owned by this user.
+	ContainerUser string
+
+	// Policy determining when action images are pulled, unless overridden by the resource; if empty, images are pulled
+	// according to their tag (see EffectivePullPolicy).
+	PullPolicy PullPolicy
+
+	// Default resource limits of action containers, unless overridden by the resource (in its init response) or by the
+	// build request (in the resource's "limits" property).
+	Limits Limits
+
+	// Maximum resource limits of action containers; resources may not request limits exceeding them. Zero values mean no
+	// maximum.
+	MaxLimits Limits
+
+	// Number of last output lines of a failed action to include in its error; zero means none. The full output of each
+	// action is captured in the ".logs/<resource>" directory of the build request's workspace.
+	OutputTailLines int
+
+	// Maximum size of each artifact handed back by an action, and of all artifacts handed back by a single action, in
+	// bytes. Actions exceeding them fail. Zero means no limit.
+	MaxArtifactSize  int64
+	MaxArtifactsSize int64
+
+	// Maximum duration of the whole build request; zero means no limit.
+	Timeout time.Duration
+
+	// Maximum duration of each action, unless overridden by the resource (in its init response) or by the build request
+	// (in the resource's "timeouts" property).
+	ActionTimeout time.Duration
+}
+
+// Returns the default build request options.
+func DefaultOptions() Options {
+	return Options{
+		Parallelism:     1,
+		FailFast:        true,
+		Project:         "default",
+		OutputTailLines: 20,
+		ActionTimeout:   10 * time.Minute,
+	}
+}
@@ -0,0 +1,150 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-errors/errors"
+)
+
+// Directory (relative to a build request's workspace) in which the output of actions is captured, in a sub-directory
+// per resource. Like artifacts, logs are kept out of resource workspaces, since actions can write to those.
+const actionLogsDir = ".logs"
+
+// Captures the output of an action's container to log files, keeping its last lines in memory for error reporting.
+type actionOutput struct {
+	stdoutFile *os.File
+	stderrFile *os.File
+	tail       *outputTail
+	events     *eventWriter
+	writers    []*tailWriter
+	Stdout     io.Writer
+	Stderr     io.Writer
+}
+
+// Creates the "<action>.stdout.log" & "<action>.stderr.log" files in the given directory (replacing any previous
+// files), keeping the given number of last output lines. Events reported by the action on its stdout are passed to the
+// given handler (and are not kept as output lines).
+func newActionOutput(ctx context.Context, dir string, action string, tailLines int, handler func(event *Event)) (*actionOutput, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed creating logs directory '%s'", dir), 0)
+	}
+	stdoutFile, err := os.Create(filepath.Join(dir, action+".stdout.log"))
+	if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed creating stdout log file of action '%s'", action), 0)
+	}
+	stderrFile, err := os.Create(filepath.Join(dir, action+".stderr.log"))
+	if err != nil {
+		//noinspection GoUnhandledErrorResult
+		stdoutFile.Close()
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed creating stderr log file of action '%s'", action), 0)
+	}
+	tail := &outputTail{max: tailLines}
+	stdoutTail, stderrTail := &tailWriter{tail: tail}, &tailWriter{tail: tail}
+	events := &eventWriter{ctx: ctx, action: action, next: stdoutTail, handler: handler}
+	return &actionOutput{
+		stdoutFile: stdoutFile,
+		stderrFile: stderrFile,
+		tail:       tail,
+		events:     events,
+		writers:    []*tailWriter{stdoutTail, stderrTail},
+		Stdout:     io.MultiWriter(stdoutFile, events),
+		Stderr:     io.MultiWriter(stderrFile, stderrTail),
+	}, nil
+}
+
+// Handles the incomplete last line of stdout (if any), which may be an event. Should only be called once the output is
+// complete.
+func (output *actionOutput) Flush() {
+	//noinspection GoUnhandledErrorResult
+	output.events.flush()
+}
+
+// Returns the last lines of output (of both stdout & stderr, in the order they were written), including incomplete
+// last lines. Should only be called once the output is complete.
+func (output *actionOutput) Tail() []string {
+	output.Flush()
+	for _, writer := range output.writers {
+		writer.flush()
+	}
+	return output.tail.Lines()
+}
+
+// Closes the log files.
+func (output *actionOutput) Close() error {
+	stdoutErr := output.stdoutFile.Close()
+	stderrErr := output.stderrFile.Close()
+	if stdoutErr != nil {
+		return errors.WrapPrefix(stdoutErr, "failed closing stdout log file", 0)
+	} else if stderrErr != nil {
+		return errors.WrapPrefix(stderrErr, "failed closing stderr log file", 0)
+	}
+	return nil
+}
+
+// Returns the given error, with the given output lines (if any) appended to its message.
+func withOutputTail(err error, lines []string) error {
+	if len(lines) == 0 {
+		return err
+	}
+	return errors.Errorf("%s\nlast %d lines of output:\n  %s", err.Error(), len(lines), strings.Join(lines, "\n  "))
+}
+
+// Keeps the last lines written to any of its writers.
+type outputTail struct {
+	mutex sync.Mutex
+	max   int
+	lines []string
+}
+
+func (tail *outputTail) add(line string) {
+	tail.mutex.Lock()
+	defer tail.mutex.Unlock()
+	if tail.max <= 0 {
+		return
+	}
+	tail.lines = append(tail.lines, line)
+	if len(tail.lines) > tail.max {
+		tail.lines = tail.lines[len(tail.lines)-tail.max:]
+	}
+}
+
+// Returns the kept lines.
+func (tail *outputTail) Lines() []string {
+	tail.mutex.Lock()
+	defer tail.mutex.Unlock()
+	return append([]string(nil), tail.lines...)
+}
+
+// Splits written output into lines, adding each complete line to an output tail. An incomplete last line is only added
+// once completed.
+type tailWriter struct {
+	tail    *outputTail
+	partial []byte
+}
+
+func (writer *tailWriter) Write(p []byte) (int, error) {
+	writer.partial = append(writer.partial, p...)
+	for {
+		newline := strings.IndexByte(string(writer.partial), '\n')
+		if newline < 0 {
+			break
+		}
+		writer.tail.add(strings.TrimRight(string(writer.partial[:newline]), "\r"))
+		writer.partial = writer.partial[newline+1:]
+	}
+	return len(p), nil
+}
+
+// Adds the incomplete last line (if any) to the output tail.
+func (writer *tailWriter) flush() {
+	if len(writer.partial) > 0 {
+		writer.tail.add(strings.TrimRight(string(writer.partial), "\r"))
+		writer.partial = nil
+	}
+}
@@ -0,0 +1,175 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-errors/errors"
+)
+
+// Matches reference expressions such as "${resources.db.outputs.host}". An escaped expression ("$${...}") is matched
+// too, so it can be unescaped rather than resolved.
+var referencePattern = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// Represents a reference to an output of another resource, as found in a resource's configuration.
+type reference struct {
+	expression string
+	resource   string
+	output     []string
+}
+
+func (ref *reference) String() string {
+	return ref.expression
+}
+
+// Parses all references in the given string. The given path is only used for error messages.
+func parseReferences(path string, value string) ([]*reference, error) {
+	var refs []*reference
+	for _, match := range referencePattern.FindAllStringSubmatch(value, -1) {
+		if strings.HasPrefix(match[0], "$$") {
+			continue
+		}
+		tokens := strings.Split(match[1], ".")
+		if len(tokens) < 4 || tokens[0] != "resources" || tokens[2] != "outputs" {
+			return nil, errors.Errorf("illegal reference '%s' at '%s' (expected '${resources.<name>.outputs.<output>}')", match[0], path)
+		}
+		for _, token := range tokens {
+			if token == "" {
+				return nil, errors.Errorf("illegal reference '%s' at '%s' (empty name)", match[0], path)
+			}
+		}
+		refs = append(refs, &reference{expression: match[0], resource: tokens[1], output: tokens[3:]})
+	}
+	return refs, nil
+}
+
+// Collects all references in the given JSON value (recursively).
+func collectReferences(path string, value interface{}) ([]*reference, error) {
+	var refs []*reference
+	switch value := value.(type) {
+	case string:
+		return parseReferences(path, value)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			childRefs, err := collectReferences(path+"/"+escapePointerToken(key), value[key])
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, childRefs...)
+		}
+	case []interface{}:
+		for i, item := range value {
+			childRefs, err := collectReferences(fmt.Sprintf("%s/%d", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, childRefs...)
+		}
+	}
+	return refs, nil
+}
+
+// Returns a copy of the given JSON value, with all references replaced by the value returned by the given resolver
+// (which also receives the JSON pointer of the string containing each reference). Strings consisting of a single
+// reference are replaced by the referenced value as-is (preserving its JSON type); references embedded in a larger
+// string are replaced by the referenced value's textual representation.
+func resolveReferences(path string, value interface{}, resolve func(path string, ref *reference) (interface{}, error)) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		refs, err := parseReferences(path, value)
+		if err != nil {
+			return nil, err
+		}
+
+		// if the whole string is a single reference, replace it with the referenced value as-is
+		if len(refs) == 1 && refs[0].expression == value {
+			resolved, err := resolve(path, refs[0])
+			if err != nil {
+				return nil, errors.WrapPrefix(err, fmt.Sprintf("unresolved reference '%s' at '%s'", refs[0], path), 0)
+			}
+			return resolved, nil
+		}
+
+		// otherwise, interpolate each reference into the string
+		var resolveErr error
+		result := referencePattern.ReplaceAllStringFunc(value, func(expression string) string {
+			if strings.HasPrefix(expression, "$$") {
+				return expression[1:]
+			} else if resolveErr != nil {
+				return expression
+			}
+			ref, _ := parseReferences(path, expression)
+			resolved, err := resolve(path, ref[0])
+			if err != nil {
+				resolveErr = errors.WrapPrefix(err, fmt.Sprintf("unresolved reference '%s' at '%s'", expression, path), 0)
+				return expression
+			}
+			if s, ok := resolved.(string); ok {
+				return s
+			}
+			b, err := json.Marshal(resolved)
+			if err != nil {
+				resolveErr = errors.WrapPrefix(err, fmt.Sprintf("unresolved reference '%s' at '%s'", expression, path), 0)
+				return expression
+			}
+			return string(b)
+		})
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		return result, nil
+
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			resolved, err := resolveReferences(path+"/"+escapePointerToken(key), item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = resolved
+		}
+		return result, nil
+
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			resolved, err := resolveReferences(fmt.Sprintf("%s/%d", path, i), item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = resolved
+		}
+		return result, nil
+
+	default:
+		return value, nil
+	}
+}
+
+// Looks up the given output path in the given outputs of the given resource.
+func lookupOutput(resource string, outputs map[string]interface{}, path []string) (interface{}, error) {
+	if outputs == nil {
+		return nil, errors.Errorf("resource '%s' provided no outputs", resource)
+	}
+	var current interface{} = outputs
+	for i, name := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("output '%s' of resource '%s' is not an object", strings.Join(path[:i], "."), resource)
+		}
+		value, ok := m[name]
+		if !ok {
+			return nil, errors.Errorf("resource '%s' has no output named '%s'", resource, strings.Join(path[:i+1], "."))
+		}
+		current = value
+	}
+	return current, nil
+}
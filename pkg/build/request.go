@@ -2,8 +2,15 @@ package build
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/go-errors/errors"
+	"io/ioutil"
+	"os"
 	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
 	. "github.com/gitzup/agent/internal/logger"
 	"github.com/gitzup/agent/pkg/assets"
@@ -15,13 +22,17 @@ type Request interface {
 	Id() string
 	Resources() map[string]Resource
 	WorkspacePath() string
-	Apply(ctx context.Context) error
+	Plan(ctx context.Context) (*Result, error)
+	Apply(ctx context.Context) (*Result, error)
 }
 
 type requestImpl struct {
 	id            string
 	resources     *map[string]*resourceImpl
+	sorted        []*resourceImpl
 	workspacePath string
+	networkID     string
+	options       Options
 }
 
 func (req *requestImpl) Id() string {
@@ -40,35 +51,198 @@ func (req *requestImpl) WorkspacePath() string {
 	return req.workspacePath
 }
 
-func (req *requestImpl) Apply(ctx context.Context) error {
+func (req *requestImpl) Apply(ctx context.Context) (*Result, error) {
 	From(ctx).Info("Applying build request")
+	return req.process(ctx, ModeApply)
+}
+
+func (req *requestImpl) Plan(ctx context.Context) (*Result, error) {
+	From(ctx).Info("Planning build request")
+	return req.process(ctx, ModePlan)
+}
+
+// Processes all resources of the request in the given mode. In "plan" mode, resources go through all phases except
+// for the "apply" phase, and thus are left untouched.
+func (req *requestImpl) process(ctx context.Context, mode Mode) (*Result, error) {
+
+	// bound the whole build request by the build timeout, if any
+	if req.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.options.Timeout)
+		defer cancel()
+	}
+
+	// protect the request's containers, networks & workspaces from garbage collection while it's being processed
+	defer markActive(req.Id())()
+
+	// process resources in dependency order, so each resource is fully applied before its dependents are processed
+	result := newResult(req, mode)
+	if err := req.createWorkspaces(); err != nil {
+		return result, result.finish(ctx, err)
+	}
+	if err := req.createNetwork(ctx); err != nil {
+		return result, result.finish(ctx, err)
+	}
+	defer req.removeNetwork(ctx)
+	err := req.processResources(ctx, func(ctx context.Context, resource *resourceImpl) error {
+		resourceResult := result.Resources[resource.Name()]
+		defer resourceResult.recordImages(resource)
+		defer resourceResult.recordEvents(resource)
+		defer resourceResult.recordArtifacts(resource)
 
-	for _, resource := range req.Resources() {
-		err := resource.Init(ctx)
+		// when planning, dependencies with pending changes may not provide their outputs yet
+		err := resourceResult.runPhase(ctx, PhaseResolve, func() error { return resource.resolveConfig(mode == ModePlan) })
 		if err != nil {
 			return err
 		}
-	}
+		resourceResult.KnownAfterApply = resource.KnownAfterApply()
 
-	for _, resource := range req.Resources() {
-		err := resource.DiscoverState(ctx)
+		err = resourceResult.runPhase(ctx, PhaseInit, func() error { return resource.Init(ctx) })
+		if err != nil {
+			return err
+		}
+
+		err = resourceResult.runPhase(ctx, PhaseState, func() error {
+			if err := req.loadStateRecord(resource); err != nil {
+				return err
+			}
+			return resource.DiscoverState(ctx)
+		})
 		if err != nil {
 			return err
 		}
-	}
 
-	for _, resource := range req.Resources() {
-		err := resource.Apply(ctx)
+		err = resourceResult.runPhase(ctx, PhasePlan, func() error { return resource.Plan(ctx) })
 		if err != nil {
 			return err
 		}
+		resourceResult.Changes = resource.Changes()
+
+		if mode == ModePlan {
+			resourceResult.Outputs = resource.Outputs()
+			if resource.Changes().Empty() {
+				resourceResult.Status = ResourceStatusUpToDate
+			} else {
+				resourceResult.Status = ResourceStatusPending
+			}
+			return nil
+		}
+
+		err = resourceResult.runPhase(ctx, PhaseApply, func() error {
+			if err := resource.Apply(ctx); err != nil {
+				return err
+			}
+			return req.saveStateRecord(resource)
+		})
+		if err != nil {
+			return err
+		}
+		resourceResult.Outputs = resource.Outputs()
+
+		if resource.Changes().Empty() {
+			resourceResult.Status = ResourceStatusUpToDate
+		} else {
+			resourceResult.Status = ResourceStatusApplied
+		}
+		return nil
+	})
+
+	// the context may expire just as the last resource completes; that only fails the request if it interrupted any resource
+	if err != nil || result.Incomplete() {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = errors.Errorf("build request timed out after %s (build timeout)", req.options.Timeout)
+		} else if ctx.Err() == context.Canceled {
+			err = errors.New("build request cancelled")
+		}
 	}
+	return result, result.finish(ctx, err)
+}
 
+// Creates the request's workspace directory, and a workspace directory for each of its resources. Directories are
+// created with fixed permissions (regardless of the process umask) so their contents are predictable to actions. The
+// request's workspace is marked as such (along with the agent instance processing it), so it can be garbage collected
+// later.
+func (req *requestImpl) createWorkspaces() error {
+	paths := []string{req.workspacePath}
+	for _, resource := range req.sorted {
+		paths = append(paths, resource.workspacePath)
+	}
+	for _, dir := range paths {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.WrapPrefix(err, fmt.Sprintf("failed creating workspace '%s'", dir), 0)
+		} else if err := os.Chmod(dir, 0755); err != nil {
+			return errors.WrapPrefix(err, fmt.Sprintf("failed setting permissions of workspace '%s'", dir), 0)
+		}
+	}
+	marker := path.Join(req.workspacePath, workspaceMarkerFile)
+	b, err := json.Marshal(workspaceMarker{RequestId: req.Id(), Instance: req.options.Instance})
+	if err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed creating workspace marker '%s'", marker), 0)
+	}
+	if err := ioutil.WriteFile(marker, b, 0644); err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed creating workspace marker '%s'", marker), 0)
+	}
 	return nil
 }
 
+// Creates a dedicated network for the request's action containers, isolating them from other build requests.
+func (req *requestImpl) createNetwork(ctx context.Context) error {
+	networkID, err := req.options.Runtime.CreateNetwork(ctx, "gitzup-"+req.Id(), req.labels())
+	if err != nil {
+		return errors.WrapPrefix(err, "failed creating build network", 0)
+	}
+	req.networkID = networkID
+	return nil
+}
+
+// Removes the request's network. Failures are only logged, since they do not affect the build request's outcome.
+func (req *requestImpl) removeNetwork(ctx context.Context) {
+	// the given context may have already expired (eg. timed out), so use a separate context for cleanup
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := req.options.Runtime.RemoveNetwork(cleanupCtx, req.networkID); err != nil {
+		From(ctx).WithError(err).Warnf("Failed removing build network '%s'", req.networkID)
+	}
+	req.networkID = ""
+}
+
+// Reads the given resource's state record as of its last successful apply (if any) from the state store.
+func (req *requestImpl) loadStateRecord(resource *resourceImpl) error {
+	if req.options.StateStore == nil {
+		return nil
+	}
+	record, err := req.options.StateStore.Get(req.options.Project, resource.Name())
+	if err != nil {
+		return err
+	}
+	resource.previous = record
+	return nil
+}
+
+// Writes the given resource's current configuration, state & outputs to the state store.
+func (req *requestImpl) saveStateRecord(resource *resourceImpl) error {
+	if req.options.StateStore == nil {
+		return nil
+	}
+	return req.options.StateStore.Put(&StateRecord{
+		Project:   req.options.Project,
+		Resource:  resource.Name(),
+		Type:      resource.Type(),
+		RequestId: req.Id(),
+		AppliedAt: time.Now(),
+		Config:    resource.Config(),
+		State:     resource.State(),
+		Outputs:   resource.Outputs(),
+	})
+}
+
 // Creates a new build request context.
-func New(id string, workspacePath string, b []byte) (req Request, err error) {
+func New(id string, workspacePath string, b []byte, options Options) (req Request, err error) {
+	if options.Runtime == nil {
+		return nil, errors.New("container runtime is required")
+	} else if !isValidPullPolicy(options.PullPolicy) {
+		return nil, errors.Errorf("invalid pull policy: %s", options.PullPolicy)
+	}
 
 	// validate & parse the build request
 	var json interface{}
@@ -84,12 +258,17 @@ func New(id string, workspacePath string, b []byte) (req Request, err error) {
 	}
 	jsonMap := json.(map[string]interface{})
 
-	// prepare our request instance
+	// prepare our request instance (workspace must be absolute, since it's bind-mounted into action containers)
+	requestWorkspacePath, err := filepath.Abs(path.Join(workspacePath, id))
+	if err != nil {
+		return nil, errors.WrapPrefix(err, "failed resolving workspace path", 0)
+	}
 	resources := make(map[string]*resourceImpl)
 	request := requestImpl{
 		id:            id,
 		resources:     &resources,
-		workspacePath: path.Join(workspacePath, id),
+		workspacePath: requestWorkspacePath,
+		options:       options,
 	}
 
 	// build the resources map
@@ -100,22 +279,99 @@ func New(id string, workspacePath string, b []byte) (req Request, err error) {
 		return nil, errors.New("resources property must be a map of resource names to resource definitions")
 	}
 	for name, resourceJson := range jsonMap["resources"].(map[string]interface{}) {
+		// resource names are used as workspace directory names, which must not escape or clash with the agent's own
+		// directories in the build request's workspace (eg. ".artifacts")
+		if strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\") {
+			return nil, errors.Errorf("illegal resource name '%s'", name)
+		}
 		resourceJsonMap := resourceJson.(map[string]interface{})
+		var dependsOn []string
+		if dependsOnJson, ok := resourceJsonMap["dependsOn"].([]interface{}); ok {
+			for _, dependency := range dependsOnJson {
+				dependsOn = append(dependsOn, dependency.(string))
+			}
+		}
+		timeouts := make(map[string]time.Duration)
+		if timeoutsJson, ok := resourceJsonMap["timeouts"].(map[string]interface{}); ok {
+			for action, timeoutJson := range timeoutsJson {
+				timeout, err := time.ParseDuration(timeoutJson.(string))
+				if err != nil {
+					return nil, errors.WrapPrefix(err, fmt.Sprintf("illegal timeout at '/resources/%s/timeouts/%s'", name, action), 0)
+				}
+				timeouts[action] = timeout
+			}
+		}
+		limits, err := parseLimits(fmt.Sprintf("/resources/%s/limits", name), resourceJsonMap["limits"])
+		if err != nil {
+			return nil, err
+		}
+		pullPolicy := options.PullPolicy
+		if pullPolicyJson, ok := resourceJsonMap["pullPolicy"].(string); ok {
+			pullPolicy = PullPolicy(pullPolicyJson)
+		}
 		resources[name] = &resourceImpl{
 			request:         &request,
 			name:            name,
 			resourceType:    resourceJsonMap["type"].(string),
+			rawConfig:       resourceJsonMap["config"],
 			resourceConfig:  resourceJsonMap["config"],
+			dependsOn:       dependsOn,
+			timeouts:        timeouts,
+			limits:          limits,
+			pullPolicy:      pullPolicy,
 			workspacePath:   path.Join(request.workspacePath, name),
 			configSchema:    nil,
 			initAction:      nil,
 			discoveryAction: nil,
 		}
+		initTimeout, err := resources[name].actionTimeout("init", "")
+		if err != nil {
+			return nil, err
+		}
+		initLimits, err := resources[name].actionLimits(Limits{})
+		if err != nil {
+			return nil, err
+		}
 		resources[name].initAction = &actionImpl{
-			resource: resources[name],
-			name:     "init",
-			image:    resources[name].resourceType,
+			runtime:    options.Runtime,
+			resource:   resources[name],
+			name:       "init",
+			image:      resources[name].resourceType,
+			timeout:    initTimeout,
+			pullPolicy: pullPolicy,
+			limits:     initLimits,
+		}
+	}
+
+	// add references to other resources' outputs as implicit dependencies
+	for name, resource := range resources {
+		refs, err := collectReferences(fmt.Sprintf("/resources/%s/config", escapePointerToken(name)), resource.rawConfig)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			if _, ok := resources[ref.resource]; !ok {
+				return nil, errors.Errorf("reference '%s' in resource '%s' refers to unknown resource '%s'", ref, name, ref.resource)
+			} else if !containsString(resource.dependsOn, ref.resource) {
+				resource.dependsOn = append(resource.dependsOn, ref.resource)
+			}
 		}
 	}
+
+	// order resources by their dependencies
+	request.sorted, err = sortResources(resources)
+	if err != nil {
+		return nil, err
+	}
+
 	return &request, nil
 }
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package build_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gitzup/agent/pkg/build"
+	"github.com/go-errors/errors"
+)
+
+// Image of the fake resource type scripted by the test fixture.
+const fakeImage = "gitzup/test:dev"
+
+// Runs build requests against a fake runtime scripting a single resource type, whose behavior is driven by each
+// resource's configuration: its apply action takes the duration in "delay" (eg. "100ms", unless its context is done
+// first), and fails if "fail" is true.
+//
+// Resources never exist beforehand, so they are always created; applying a resource exposes its configuration as its
+// outputs.
+type fixture struct {
+	t         *testing.T
+	runtime   *build.FakeRuntime
+	workspace string
+	options   build.Options
+
+	mutex      sync.Mutex
+	started    chan string
+	applied    []string
+	configs    map[string]map[string]interface{}
+	running    int
+	maxRunning int
+}
+
+func newFixture(t *testing.T) *fixture {
+	workspace, err := ioutil.TempDir("", "gitzup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fixture{
+		t:         t,
+		runtime:   build.NewFakeRuntime(),
+		workspace: workspace,
+		options:   build.DefaultOptions(),
+		started:   make(chan string, 100),
+		configs:   make(map[string]map[string]interface{}),
+	}
+	f.options.Runtime = f.runtime
+	f.runtime.Respond(fakeImage, "init", map[string]interface{}{
+		"configSchema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"port": map[string]interface{}{"type": "integer"},
+				"url":  map[string]interface{}{"type": "string", "pattern": "^http://"},
+			},
+		},
+		"stateAction": map[string]interface{}{"image": fakeImage},
+		"applyAction": map[string]interface{}{"image": fakeImage},
+	})
+	f.runtime.Respond(fakeImage, "state", map[string]interface{}{"state": nil})
+	f.runtime.Handle(fakeImage, "apply", f.apply)
+	return f
+}
+
+// Removes the fixture's workspace.
+func (f *fixture) close() {
+	os.RemoveAll(f.workspace)
+}
+
+// Handles the apply action of a fake resource.
+func (f *fixture) apply(ctx context.Context, spec *build.ContainerSpec) (interface{}, error) {
+	resource := spec.Input.(map[string]interface{})["resource"].(map[string]interface{})
+	name := resource["name"].(string)
+	config, _ := resource["config"].(map[string]interface{})
+
+	f.mutex.Lock()
+	f.configs[name] = config
+	f.running++
+	if f.running > f.maxRunning {
+		f.maxRunning = f.running
+	}
+	f.mutex.Unlock()
+	defer func() {
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		f.running--
+	}()
+	f.started <- name
+
+	if delay, ok := config["delay"].(string); ok {
+		duration, err := time.ParseDuration(delay)
+		if err != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(duration):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if fail, _ := config["fail"].(bool); fail {
+		return nil, errors.Errorf("resource '%s' failed on purpose", name)
+	}
+
+	f.mutex.Lock()
+	f.applied = append(f.applied, name)
+	f.mutex.Unlock()
+	return map[string]interface{}{"state": config, "outputs": config}, nil
+}
+
+// Creates a build request with the given resources (in JSON) and the fixture's options.
+func (f *fixture) request(resources string) build.Request {
+	id := strings.ToLower(strings.Replace(f.t.Name(), "/", "-", -1))
+	request, err := build.New(id, f.workspace, []byte(fmt.Sprintf(`{"resources": %s}`, resources)), f.options)
+	if err != nil {
+		f.t.Fatalf("failed creating build request: %s", err)
+	}
+	return request
+}
+
+// Asserts the given resources of the given result have the given statuses.
+func (f *fixture) assertStatuses(result *build.Result, statuses map[string]build.ResourceStatus) {
+	for name, status := range statuses {
+		if actual := result.Resources[name].Status; actual != status {
+			f.t.Errorf("expected resource '%s' to be %s, got %s (%+v)", name, status, actual, result.Resources[name].Error)
+		}
+	}
+}
+
+func TestApplyResolvesReferences(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+
+	result, err := f.request(`{
+		"net": {"type": "gitzup/test:dev", "config": {"port": 8080}},
+		"app": {"type": "gitzup/test:dev", "config": {
+			"port": "${resources.net.outputs.port}",
+			"url": "http://localhost:${resources.net.outputs.port}"
+		}}
+	}`).Apply(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{"net": build.ResourceStatusApplied, "app": build.ResourceStatusApplied})
+	expected := map[string]interface{}{"port": float64(8080), "url": "http://localhost:8080"}
+	if !reflect.DeepEqual(f.configs["app"], expected) {
+		t.Errorf("expected configuration %v, got %v", expected, f.configs["app"])
+	}
+}
+
+func TestPlanDefersReferencesToPendingResources(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+
+	result, err := f.request(`{
+		"net": {"type": "gitzup/test:dev", "config": {"port": 8080}},
+		"app": {"type": "gitzup/test:dev", "config": {
+			"port": "${resources.net.outputs.port}",
+			"url": "http://localhost:${resources.net.outputs.port}"
+		}}
+	}`).Plan(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{"net": build.ResourceStatusPending, "app": build.ResourceStatusPending})
+	if expected := []string{"/port", "/url"}; !reflect.DeepEqual(result.Resources["app"].KnownAfterApply, expected) {
+		t.Errorf("expected %v to be known after apply, got %v", expected, result.Resources["app"].KnownAfterApply)
+	}
+	if len(f.applied) > 0 {
+		t.Errorf("expected no resources to be applied when planning, got %v", f.applied)
+	}
+}
+
+func TestApplyFailsReferencesToMissingOutputs(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+
+	result, err := f.request(`{
+		"net": {"type": "gitzup/test:dev", "config": {}},
+		"app": {"type": "gitzup/test:dev", "config": {"port": "${resources.net.outputs.port}"}}
+	}`).Apply(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{"net": build.ResourceStatusApplied, "app": build.ResourceStatusFailed})
+	if phase := result.Resources["app"].Error.Phase; phase != build.PhaseResolve {
+		t.Errorf("expected resource 'app' to fail in the %s phase, got %s", build.PhaseResolve, phase)
+	}
+}
+
+func TestApplyEnforcesActionTimeout(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+
+	result, err := f.request(`{
+		"app": {"type": "gitzup/test:dev", "config": {"delay": "10s"}, "timeouts": {"apply": "50ms"}}
+	}`).Apply(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{"app": build.ResourceStatusFailed})
+	if result.Status != build.StatusFailed {
+		t.Errorf("expected build request to fail, got %s", result.Status)
+	} else if message := result.Resources["app"].Error.Message; !strings.Contains(message, "action timeout") {
+		t.Errorf("expected an action timeout error, got: %s", message)
+	}
+}
+
+func TestApplyEnforcesBuildTimeout(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+	f.options.Timeout = 50 * time.Millisecond
+
+	result, err := f.request(`{
+		"app": {"type": "gitzup/test:dev", "config": {"delay": "10s"}},
+		"web": {"type": "gitzup/test:dev", "config": {}, "dependsOn": ["app"]}
+	}`).Apply(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "build timeout") {
+		t.Fatalf("expected a build timeout error, got: %v", err)
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{"app": build.ResourceStatusFailed, "web": build.ResourceStatusSkipped})
+	if result.Status != build.StatusFailed {
+		t.Errorf("expected build request to fail, got %s", result.Status)
+	}
+}
+
+func TestApplyCancellation(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-f.started
+		cancel()
+	}()
+	result, err := f.request(`{
+		"app": {"type": "gitzup/test:dev", "config": {"delay": "10s"}},
+		"web": {"type": "gitzup/test:dev", "config": {}, "dependsOn": ["app"]}
+	}`).Apply(ctx)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{"app": build.ResourceStatusCancelled, "web": build.ResourceStatusSkipped})
+	if result.Status != build.StatusCancelled {
+		t.Errorf("expected build request to be cancelled, got %s", result.Status)
+	}
+}
@@ -2,9 +2,13 @@ package build
 
 import (
 	"context"
+	"fmt"
 	. "github.com/gitzup/agent/internal/logger"
 	"github.com/gitzup/agent/pkg/assets"
 	"github.com/go-errors/errors"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Represents a single resource in a build request.
@@ -13,10 +17,16 @@ type Resource interface {
 	Name() string
 	Type() string
 	Config() interface{}
+	DependsOn() []string
 	ConfigSchema() *assets.Schema
 	WorkspacePath() string
+	State() interface{}
+	Changes() Diff
+	Outputs() map[string]interface{}
+	KnownAfterApply() []string
 	Init(ctx context.Context) error
 	DiscoverState(ctx context.Context) error
+	Plan(ctx context.Context) error
 	Apply(ctx context.Context) error
 }
 
@@ -24,11 +34,24 @@ type resourceImpl struct {
 	request         Request
 	name            string
 	resourceType    string
+	rawConfig       interface{}
 	resourceConfig  interface{}
+	dependsOn       []string
+	timeouts        map[string]time.Duration
+	limits          Limits
+	pullPolicy      PullPolicy
 	workspacePath   string
 	configSchema    *assets.Schema
 	initAction      Action
 	discoveryAction Action
+	planAction      Action
+	applyAction     Action
+	previous        *StateRecord
+	state           interface{}
+	planned         bool
+	changes         Diff
+	outputs         map[string]interface{}
+	knownAfterApply []string
 }
 
 type resourceInitRequest struct {
@@ -36,13 +59,59 @@ type resourceInitRequest struct {
 	Resource Resource `json:"resource"`
 }
 
+type resourceActionSpec struct {
+	Image      string   `json:"image"`
+	Entrypoint []string `json:"entrypoint"`
+	Cmd        []string `json:"cmd"`
+	Network    string   `json:"network"`
+	Timeout    string   `json:"timeout"`
+}
+
 type resourceInitResponse struct {
-	ConfigSchema interface{} `json:"configSchema"`
-	StateAction  struct {
-		Image      string   `json:"image"`
-		Entrypoint []string `json:"entrypoint"`
-		Cmd        []string `json:"cmd"`
-	} `json:"stateAction"`
+	ConfigSchema interface{}            `json:"configSchema"`
+	StateAction  resourceActionSpec     `json:"stateAction"`
+	PlanAction   *resourceActionSpec    `json:"planAction"`
+	ApplyAction  *resourceActionSpec    `json:"applyAction"`
+	Limits       map[string]interface{} `json:"limits"`
+}
+
+type resourceInfo struct {
+	Name   string      `json:"name"`
+	Type   string      `json:"type"`
+	Config interface{} `json:"config,omitempty"`
+}
+
+type resourceStateRequest struct {
+	RequestId string       `json:"requestId"`
+	Resource  resourceInfo `json:"resource"`
+	Previous  *StateRecord `json:"previous,omitempty"`
+}
+
+type resourceStateResponse struct {
+	State   interface{}            `json:"state"`
+	Outputs map[string]interface{} `json:"outputs"`
+}
+
+type resourcePlanRequest struct {
+	RequestId string       `json:"requestId"`
+	Resource  resourceInfo `json:"resource"`
+	State     interface{}  `json:"state"`
+}
+
+type resourcePlanResponse struct {
+	Changes Diff `json:"changes"`
+}
+
+type resourceApplyRequest struct {
+	RequestId string       `json:"requestId"`
+	Resource  resourceInfo `json:"resource"`
+	State     interface{}  `json:"state"`
+	Changes   Diff         `json:"changes"`
+}
+
+type resourceApplyResponse struct {
+	State   interface{}            `json:"state"`
+	Outputs map[string]interface{} `json:"outputs"`
 }
 
 func (res *resourceImpl) Request() Request {
@@ -61,6 +130,10 @@ func (res *resourceImpl) Config() interface{} {
 	return res.resourceConfig
 }
 
+func (res *resourceImpl) DependsOn() []string {
+	return res.dependsOn
+}
+
 func (res *resourceImpl) ConfigSchema() *assets.Schema {
 	return res.configSchema
 }
@@ -69,6 +142,118 @@ func (res *resourceImpl) WorkspacePath() string {
 	return res.workspacePath
 }
 
+func (res *resourceImpl) State() interface{} {
+	return res.state
+}
+
+func (res *resourceImpl) Changes() Diff {
+	return res.changes
+}
+
+func (res *resourceImpl) Outputs() map[string]interface{} {
+	return res.outputs
+}
+
+// Returns the JSON pointers (relative to the resource's configuration) of configuration values that reference outputs
+// which are only known once pending changes are applied. Such values are left unresolved when planning.
+func (res *resourceImpl) KnownAfterApply() []string {
+	return res.knownAfterApply
+}
+
+// Resolves references to other resources' outputs in the resource's raw configuration; the result becomes the
+// resource's configuration. All referenced resources must have been processed beforehand.
+//
+// If "deferPending" is true, references to missing outputs of resources with pending changes are left unresolved,
+// since such outputs are only known once those changes are applied. The configuration values containing such references
+// are recorded, so that they can be excluded from validation and reported as "known after apply".
+func (res *resourceImpl) resolveConfig(deferPending bool) error {
+	configPath := fmt.Sprintf("/resources/%s/config", escapePointerToken(res.Name()))
+	var knownAfterApply []string
+	config, err := resolveReferences(
+		configPath,
+		res.rawConfig,
+		func(path string, ref *reference) (interface{}, error) {
+			target, ok := res.Request().Resources()[ref.resource]
+			if !ok {
+				return nil, errors.Errorf("unknown resource '%s'", ref.resource)
+			}
+			value, err := lookupOutput(ref.resource, target.Outputs(), ref.output)
+			if err != nil && deferPending && !target.Changes().Empty() {
+				path = strings.TrimPrefix(path, configPath)
+				if len(knownAfterApply) == 0 || knownAfterApply[len(knownAfterApply)-1] != path {
+					knownAfterApply = append(knownAfterApply, path)
+				}
+				return ref.expression, nil
+			}
+			return value, err
+		})
+	if err != nil {
+		return err
+	}
+	sort.Strings(knownAfterApply)
+	res.resourceConfig = config
+	res.knownAfterApply = knownAfterApply
+	return nil
+}
+
+func (res *resourceImpl) info() resourceInfo {
+	return resourceInfo{Name: res.Name(), Type: res.Type(), Config: res.Config()}
+}
+
+func (res *resourceImpl) newAction(name string, spec *resourceActionSpec, declaredLimits Limits) (Action, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	timeout, err := res.actionTimeout(name, spec.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := res.actionLimits(declaredLimits)
+	if err != nil {
+		return nil, err
+	}
+	return &actionImpl{
+		runtime:    res.request.(*requestImpl).options.Runtime,
+		resource:   res,
+		name:       name,
+		image:      spec.Image,
+		entrypoint: spec.Entrypoint,
+		cmd:        spec.Cmd,
+		timeout:    timeout,
+		pullPolicy: res.pullPolicy,
+		limits:     limits,
+		network:    spec.Network,
+	}, nil
+}
+
+// Returns the timeout for the given action: the timeout specified for it in the build request takes precedence, then
+// the timeout declared by the resource (if any), and finally the default action timeout.
+func (res *resourceImpl) actionTimeout(action string, declared string) (time.Duration, error) {
+	if timeout, ok := res.timeouts[action]; ok {
+		return timeout, nil
+	} else if declared != "" {
+		timeout, err := time.ParseDuration(declared)
+		if err != nil {
+			return 0, errors.WrapPrefix(err, fmt.Sprintf("illegal timeout for action '%s'", action), 0)
+		}
+		return timeout, nil
+	} else {
+		return res.request.(*requestImpl).options.ActionTimeout, nil
+	}
+}
+
+// Returns the resource limits for the resource's actions: limits specified in the build request take precedence, then
+// the given limits declared by the resource (if any), and finally the agent's default limits. The resulting limits are
+// capped by the agent's maximum limits.
+func (res *resourceImpl) actionLimits(declared Limits) (Limits, error) {
+	options := res.request.(*requestImpl).options
+	limits, err := options.Limits.Merge(declared).Merge(res.limits).Enforce(options.MaxLimits)
+	if err != nil {
+		return limits, errors.WrapPrefix(err, fmt.Sprintf("illegal limits for resource '%s'", res.Name()), 0)
+	}
+	return limits, nil
+}
+
 func (res *resourceImpl) Init(ctx context.Context) error {
 	ctx = context.WithValue(ctx, "resource", res.Name())
 
@@ -98,24 +283,38 @@ func (res *resourceImpl) Init(ctx context.Context) error {
 		assets.GetBuildResponseSchema(),
 		assets.GetInitRequestSchema(),
 		assets.GetInitResponseSchema(),
+		assets.GetStateRequestSchema(),
+		assets.GetStateResponseSchema(),
+		assets.GetChangeSchema(),
+		assets.GetLimitsSchema(),
+		assets.GetPlanRequestSchema(),
+		assets.GetPlanResponseSchema(),
+		assets.GetApplyRequestSchema(),
+		assets.GetApplyResponseSchema(),
 	)
 	if err != nil {
 		return err
 	}
 	res.configSchema = resourceConfigSchema
 
-	// use the configuration schema to validate the resource's configuration
-	if err := res.configSchema.Validate(res.Config()); err != nil {
+	// use the configuration schema to validate the resource's configuration (except for values not known yet)
+	if err := res.configSchema.ValidateIgnoring(res.Config(), res.knownAfterApply...); err != nil {
 		return err
 	}
 
-	// read and set the resource's state discovery action
-	res.discoveryAction = &actionImpl{
-		resource:   res,
-		name:       "state",
-		image:      response.StateAction.Image,
-		entrypoint: response.StateAction.Entrypoint,
-		cmd:        response.StateAction.Cmd,
+	// read and set the resource's actions (plan & apply actions are optional)
+	limits, err := parseLimits("/limits", response.Limits)
+	if err != nil {
+		return err
+	}
+	if res.discoveryAction, err = res.newAction("state", &response.StateAction, limits); err != nil {
+		return err
+	}
+	if res.planAction, err = res.newAction("plan", response.PlanAction, limits); err != nil {
+		return err
+	}
+	if res.applyAction, err = res.newAction("apply", response.ApplyAction, limits); err != nil {
+		return err
 	}
 
 	return nil
@@ -126,15 +325,117 @@ func (res *resourceImpl) DiscoverState(ctx context.Context) error {
 
 	From(ctx).Info("Discovering state")
 
-	// TODO eric: implement
-	panic("not implemented")
+	if res.discoveryAction == nil {
+		return errors.New("resource has not been initialized")
+	}
+
+	// invoke the state discovery action, sending it the resource's desired configuration
+	var response resourceStateResponse
+	err := res.discoveryAction.Invoke(
+		ctx,
+		&resourceStateRequest{
+			RequestId: res.Request().Id(),
+			Resource:  res.info(),
+			Previous:  res.previous,
+		},
+		assets.GetStateResponseSchema(),
+		&response,
+	)
+	if err != nil {
+		return errors.WrapPrefix(err, "failed discovering resource state", 0)
+	}
+
+	// save the discovered state & outputs for later phases
+	res.state = response.State
+	res.outputs = response.Outputs
+	if res.state == nil {
+		From(ctx).Info("Resource does not exist")
+	}
+
+	return nil
+}
+
+func (res *resourceImpl) Plan(ctx context.Context) error {
+	ctx = context.WithValue(ctx, "resource", res.Name())
+
+	From(ctx).Info("Planning")
+
+	if res.discoveryAction == nil {
+		return errors.New("resource has not been initialized")
+	}
+
+	// if the resource provides no plan action, compute the changes ourselves
+	if res.planAction == nil {
+		res.changes = ComputeDiff(res.State(), res.Config())
+	} else {
+		var response resourcePlanResponse
+		err := res.planAction.Invoke(
+			ctx,
+			&resourcePlanRequest{
+				RequestId: res.Request().Id(),
+				Resource:  res.info(),
+				State:     res.State(),
+			},
+			assets.GetPlanResponseSchema(),
+			&response,
+		)
+		if err != nil {
+			return errors.WrapPrefix(err, "failed planning resource changes", 0)
+		}
+		res.changes = response.Changes
+	}
+
+	res.planned = true
+	for _, change := range res.changes {
+		From(ctx).Debugf("Pending change: %s", change)
+	}
+
+	return nil
 }
 
 func (res *resourceImpl) Apply(ctx context.Context) error {
 	ctx = context.WithValue(ctx, "resource", res.Name())
 
-	From(ctx).Info("Applying")
+	// plan the changes, unless that was already done
+	if !res.planned {
+		if err := res.Plan(ctx); err != nil {
+			return err
+		}
+	}
+
+	// only invoke the apply action if there's anything to apply
+	if res.changes.Empty() {
+		From(ctx).Info("Resource is up-to-date")
+		return nil
+	} else if res.applyAction == nil {
+		return errors.Errorf("resource has %d pending change(s), but provides no apply action", len(res.changes))
+	}
+
+	From(ctx).Infof("Applying %d change(s)", len(res.changes))
+
+	var response resourceApplyResponse
+	err := res.applyAction.Invoke(
+		ctx,
+		&resourceApplyRequest{
+			RequestId: res.Request().Id(),
+			Resource:  res.info(),
+			State:     res.State(),
+			Changes:   res.changes,
+		},
+		assets.GetApplyResponseSchema(),
+		&response,
+	)
+	if err != nil {
+		return errors.WrapPrefix(err, "failed applying resource changes", 0)
+	}
+
+	// if the resource reported its new state & outputs, retain them
+	if response.State != nil {
+		res.state = response.State
+	}
+	if response.Outputs != nil {
+		res.outputs = response.Outputs
+	}
 
-	// TODO eric: implement
-	panic("not implemented")
+	return nil
 }
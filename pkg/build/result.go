@@ -0,0 +1,235 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gitzup/agent/pkg/assets"
+	"github.com/go-errors/errors"
+)
+
+// Overall outcome of a build request.
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Mode in which a build request is processed.
+type Mode string
+
+const (
+	// Resources are planned and their changes applied.
+	ModeApply Mode = "apply"
+
+	// Resources are only planned; no changes are applied.
+	ModePlan Mode = "plan"
+)
+
+// Outcome of a single resource in a build request.
+type ResourceStatus string
+
+const (
+	// Changes were applied to the resource.
+	ResourceStatusApplied ResourceStatus = "applied"
+
+	// The resource required no changes.
+	ResourceStatusUpToDate ResourceStatus = "up-to-date"
+
+	// The resource has changes that were planned but not applied.
+	ResourceStatusPending ResourceStatus = "pending"
+
+	// Processing the resource failed.
+	ResourceStatusFailed ResourceStatus = "failed"
+
+	// Processing the resource was interrupted because the build request was cancelled or aborted.
+	ResourceStatusCancelled ResourceStatus = "cancelled"
+
+	// The resource was not processed, eg. because a resource it depends on failed.
+	ResourceStatusSkipped ResourceStatus = "skipped"
+)
+
+// Names of the phases each resource goes through.
+const (
+	PhaseResolve = "resolve"
+	PhaseInit    = "init"
+	PhaseState   = "state"
+	PhasePlan    = "plan"
+	PhaseApply   = "apply"
+)
+
+// Details of an error that failed a build request or a resource.
+type ErrorDetails struct {
+	Message string `json:"message"`
+	Phase   string `json:"phase,omitempty"`
+}
+
+// Timing of a single phase of a resource.
+type PhaseTiming struct {
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Image used by an action.
+type ImageDetails struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+}
+
+// Result of a single resource in a build request.
+type ResourceResult struct {
+	Type            string                   `json:"type"`
+	Status          ResourceStatus           `json:"status"`
+	Phases          map[string]*PhaseTiming  `json:"phases,omitempty"`
+	Images          map[string]*ImageDetails `json:"images,omitempty"`
+	Changes         Diff                     `json:"changes,omitempty"`
+	KnownAfterApply []string                 `json:"knownAfterApply,omitempty"`
+	Outputs         map[string]interface{}   `json:"outputs,omitempty"`
+	Events          []Event                  `json:"events,omitempty"`
+	Artifacts       []Artifact               `json:"artifacts,omitempty"`
+	Error           *ErrorDetails            `json:"error,omitempty"`
+}
+
+// Result of a build request.
+type Result struct {
+	RequestId  string                     `json:"requestId"`
+	Mode       Mode                       `json:"mode"`
+	Status     Status                     `json:"status"`
+	StartedAt  time.Time                  `json:"startedAt"`
+	FinishedAt time.Time                  `json:"finishedAt"`
+	DurationMs int64                      `json:"durationMs"`
+	Resources  map[string]*ResourceResult `json:"resources"`
+	Error      *ErrorDetails              `json:"error,omitempty"`
+}
+
+// Creates a new result for the given request, with all resources marked as skipped.
+func newResult(req *requestImpl, mode Mode) *Result {
+	result := &Result{
+		RequestId: req.Id(),
+		Mode:      mode,
+		StartedAt: time.Now(),
+		Resources: make(map[string]*ResourceResult, len(req.sorted)),
+	}
+	for _, res := range req.sorted {
+		result.Resources[res.Name()] = &ResourceResult{
+			Type:   res.Type(),
+			Status: ResourceStatusSkipped,
+			Phases: make(map[string]*PhaseTiming),
+		}
+	}
+	return result
+}
+
+// Invokes the given function as the given phase of this resource result, recording its timing. If the function fails,
+// the resource result is marked as failed in this phase (or as cancelled, if the given context was cancelled).
+func (result *ResourceResult) runPhase(ctx context.Context, phase string, f func() error) error {
+	timing := &PhaseTiming{StartedAt: time.Now()}
+	result.Phases[phase] = timing
+	err := f()
+	timing.DurationMs = time.Since(timing.StartedAt).Nanoseconds() / int64(time.Millisecond)
+	if err != nil && ctx.Err() == context.Canceled {
+		result.Status = ResourceStatusCancelled
+		result.Error = &ErrorDetails{Message: "cancelled", Phase: phase}
+	} else if err != nil {
+		result.Status = ResourceStatusFailed
+		result.Error = &ErrorDetails{Message: err.Error(), Phase: phase}
+	}
+	return err
+}
+
+// Records the images used by the given resource's actions (only actions that were invoked are recorded).
+func (result *ResourceResult) recordImages(resource *resourceImpl) {
+	actions := []Action{resource.initAction, resource.discoveryAction, resource.planAction, resource.applyAction}
+	for _, action := range actions {
+		if action != nil && action.Digest() != "" {
+			if result.Images == nil {
+				result.Images = make(map[string]*ImageDetails)
+			}
+			result.Images[action.Name()] = &ImageDetails{Image: action.Image(), Digest: action.Digest()}
+		}
+	}
+}
+
+// Records the events reported by the given resource's actions, in the order the actions were invoked.
+func (result *ResourceResult) recordEvents(resource *resourceImpl) {
+	actions := []Action{resource.initAction, resource.discoveryAction, resource.planAction, resource.applyAction}
+	for _, action := range actions {
+		if action != nil {
+			result.Events = append(result.Events, action.Events()...)
+		}
+	}
+}
+
+// Records the artifacts handed back by the given resource's actions, in the order the actions were invoked.
+func (result *ResourceResult) recordArtifacts(resource *resourceImpl) {
+	actions := []Action{resource.initAction, resource.discoveryAction, resource.planAction, resource.applyAction}
+	for _, action := range actions {
+		if action != nil {
+			result.Artifacts = append(result.Artifacts, action.Artifacts()...)
+		}
+	}
+}
+
+// Returns true if the configuration value at the given JSON pointer (or the value containing it) is only known once
+// pending changes of the resource's dependencies are applied.
+func (result *ResourceResult) IsKnownAfterApply(path string) bool {
+	for _, pointer := range result.KnownAfterApply {
+		if path == pointer || strings.HasPrefix(path, pointer+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if any resource in this result was never processed, or was interrupted while being processed.
+func (result *Result) Incomplete() bool {
+	for _, res := range result.Resources {
+		if res.Status == ResourceStatusSkipped || res.Status == ResourceStatusCancelled {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if any resource in this result has changes that were planned but not applied.
+func (result *Result) HasPendingChanges() bool {
+	for _, res := range result.Resources {
+		if res.Status == ResourceStatusPending {
+			return true
+		}
+	}
+	return false
+}
+
+// Finalizes this result, setting its status & timing according to the given error (which may be nil) and whether the
+// given context was cancelled, and validates it against the build response schema. A result failing validation is
+// marked as failed, with the validation error as its error.
+func (result *Result) finish(ctx context.Context, err error) error {
+	result.FinishedAt = time.Now()
+	result.DurationMs = result.FinishedAt.Sub(result.StartedAt).Nanoseconds() / int64(time.Millisecond)
+	if err != nil && ctx.Err() == context.Canceled {
+		result.Status = StatusCancelled
+		result.Error = &ErrorDetails{Message: err.Error()}
+	} else if err != nil {
+		result.Status = StatusFailed
+		result.Error = &ErrorDetails{Message: err.Error()}
+	} else {
+		result.Status = StatusSucceeded
+	}
+
+	b, marshalErr := json.Marshal(result)
+	if marshalErr != nil {
+		err = errors.WrapPrefix(marshalErr, "failed serializing build result", 0)
+	} else if validationErr := assets.GetBuildResponseSchema().Validate(b); validationErr != nil {
+		err = errors.WrapPrefix(validationErr, "build result is illegal", 0)
+	} else {
+		return err
+	}
+	result.Status = StatusFailed
+	result.Error = &ErrorDetails{Message: err.Error()}
+	return err
+}
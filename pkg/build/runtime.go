@@ -0,0 +1,155 @@
+package build
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// Network mode of containers with networking disabled.
+const NetworkNone = "none"
+
+// Policy determining when images are pulled from their registry.
+type PullPolicy string
+
+const (
+	// Always pull images, even if present locally (eg. to pick up changes to mutable tags).
+	PullAlways PullPolicy = "Always"
+
+	// Only pull images which are not present locally.
+	PullIfNotPresent PullPolicy = "IfNotPresent"
+
+	// Never pull images; fail if an image is not present locally.
+	PullNever PullPolicy = "Never"
+)
+
+// Returns the pull policy to use for the given image: the given policy if set; otherwise "Always" for untagged images or
+// images tagged "latest", and "IfNotPresent" for all other images (including images referenced by digest).
+func EffectivePullPolicy(policy PullPolicy, image string) PullPolicy {
+	if policy != "" {
+		return policy
+	} else if strings.Contains(image, "@") {
+		return PullIfNotPresent
+	} else if colon := strings.LastIndex(image, ":"); colon < 0 || colon < strings.LastIndex(image, "/") {
+		return PullAlways
+	} else if image[colon+1:] == "latest" {
+		return PullAlways
+	}
+	return PullIfNotPresent
+}
+
+func isValidPullPolicy(policy PullPolicy) bool {
+	switch policy {
+	case "", PullAlways, PullIfNotPresent, PullNever:
+		return true
+	default:
+		return false
+	}
+}
+
+// Specification of a container to run for an action.
+type ContainerSpec struct {
+	Name       string
+	Image      string
+	Entrypoint []string
+	Cmd        []string
+	Env        []string
+	Volumes    map[string]struct{}
+	Mounts     []Mount
+	Labels     map[string]string
+
+	// Resource limits of the container.
+	Limits Limits
+
+	// Network to attach the container to (as returned by CreateNetwork), or NetworkNone to disable networking.
+	Network string
+
+	// User (and optionally group) to run the container as, in "<user>[:<group>]" format; if empty, the image's default
+	// user is used.
+	User string
+
+	// Value to serialize as JSON and send to the container's stdin; if nil, no input is sent.
+	Input interface{}
+
+	// Writers receiving the container's stdout & stderr output, respectively (in addition to the agent's log); either
+	// may be nil.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// A host directory bind-mounted into a container.
+type Mount struct {
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+// Information about a container, as reported by the container runtime.
+type ContainerInfo struct {
+	ID       string
+	Name     string
+	Image    string
+	Running  bool
+	ExitCode int
+
+	// Whether the container was killed for exceeding its memory limit.
+	OOMKilled bool
+
+	Labels  map[string]string
+	Created time.Time
+}
+
+// Information about a network, as reported by the container runtime.
+type NetworkInfo struct {
+	ID      string
+	Name    string
+	Labels  map[string]string
+	Created time.Time
+}
+
+// Callback invoked by the container runtime with the ID of a running (or exited) container. Handlers must return
+// promptly once the given context is done, since the runtime waits for them before removing the container.
+type ContainerHandler func(ctx context.Context, containerID string) error
+
+// Container runtime used to run resource actions. Implementations must be safe for concurrent use.
+type Runtime interface {
+	// Pulls the given image (which may be referenced by tag or by digest) according to the given pull policy (see
+	// EffectivePullPolicy), and returns the digest of the image that will be used.
+	Pull(ctx context.Context, image string, policy PullPolicy) (string, error)
+
+	// Creates & runs a container according to the given specification, and waits for it to exit. The pre-exit handler
+	// (if any) is invoked once the container is started, and the post-exit handler (if any) is invoked once it exits
+	// successfully; the container is only removed after both handlers return. All output of the container is written to
+	// the specification's writers before the post-exit handler is invoked. Returns an error if the container could not
+	// be run, exited with a non-zero exit code, or if either handler failed; if the container was killed for exceeding
+	// its memory limit, the error is the one returned by OOMKilledError. If the context is done before the container
+	// exits, the container is stopped. If a container of the same name exists, it is replaced if stale (see
+	// IsStaleContainer), and the run fails otherwise.
+	Run(ctx context.Context, spec *ContainerSpec, preExitHandler ContainerHandler, postExitHandler ContainerHandler) error
+
+	// Returns the contents of the file at the given path in the given container.
+	CopyFrom(ctx context.Context, containerID string, path string) ([]byte, error)
+
+	// Returns a tar archive of the file or directory at the given path in the given container (entries are rooted at
+	// the path's base name), or nil if the path does not exist. The caller must close the returned archive.
+	CopyArchiveFrom(ctx context.Context, containerID string, path string) (io.ReadCloser, error)
+
+	// Returns information about the given container.
+	Inspect(ctx context.Context, containerID string) (*ContainerInfo, error)
+
+	// Returns all containers (running or not) labeled as created by a Gitzup agent (see LabelManaged).
+	ListContainers(ctx context.Context) ([]ContainerInfo, error)
+
+	// Removes the given container, stopping it first if it's running.
+	RemoveContainer(ctx context.Context, containerID string) error
+
+	// Creates an isolated network with the given name & labels, returning its ID.
+	CreateNetwork(ctx context.Context, name string, labels map[string]string) (string, error)
+
+	// Returns all networks labeled as created by a Gitzup agent (see LabelManaged).
+	ListNetworks(ctx context.Context) ([]NetworkInfo, error)
+
+	// Removes the given network.
+	RemoveNetwork(ctx context.Context, networkID string) error
+}
@@ -0,0 +1,129 @@
+package build_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gitzup/agent/pkg/build"
+)
+
+func TestEffectivePullPolicy(t *testing.T) {
+	digest := "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		policy   build.PullPolicy
+		image    string
+		expected build.PullPolicy
+	}{
+		{policy: build.PullNever, image: "gitzup/test", expected: build.PullNever},
+		{policy: build.PullAlways, image: "gitzup/test:1.0", expected: build.PullAlways},
+		{policy: build.PullIfNotPresent, image: "gitzup/test:latest", expected: build.PullIfNotPresent},
+		{image: "gitzup/test", expected: build.PullAlways},
+		{image: "gitzup/test:latest", expected: build.PullAlways},
+		{image: "registry:5000/gitzup/test", expected: build.PullAlways},
+		{image: "registry:5000/gitzup/test:latest", expected: build.PullAlways},
+		{image: "gitzup/test:1.0", expected: build.PullIfNotPresent},
+		{image: "registry:5000/gitzup/test:1.0", expected: build.PullIfNotPresent},
+		{image: "gitzup/test@" + digest, expected: build.PullIfNotPresent},
+		{image: "gitzup/test:latest@" + digest, expected: build.PullIfNotPresent},
+	}
+	for _, test := range tests {
+		if policy := build.EffectivePullPolicy(test.policy, test.image); policy != test.expected {
+			t.Errorf("expected pull policy '%s' for image '%s' (with policy '%s'), got '%s'", test.expected, test.image, test.policy, policy)
+		}
+	}
+}
+
+func TestNewRejectsInvalidPullPolicy(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+	f.options.PullPolicy = "Sometimes"
+
+	_, err := build.New("invalid-pull-policy", f.workspace, []byte(`{"resources": {}}`), f.options)
+	if err == nil {
+		t.Error("expected invalid pull policy to be rejected")
+	}
+}
+
+func TestApplyUsesResourcePullPolicy(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+	f.options.PullPolicy = build.PullAlways
+
+	result, err := f.request(`{
+		"web": {"type": "gitzup/test:dev", "config": {}, "pullPolicy": "Never"}
+	}`).Apply(context.Background())
+	if err == nil {
+		t.Fatal("expected an error, since the image is not present and must not be pulled")
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{"web": build.ResourceStatusFailed})
+	if pulled := f.runtime.Pulled(); len(pulled) != 0 {
+		t.Errorf("expected no images to be pulled, got %v", pulled)
+	}
+
+	f.runtime.AddImage(fakeImage, "sha256:local")
+	result, err = f.request(`{
+		"web": {"type": "gitzup/test:dev", "config": {}, "pullPolicy": "Never"},
+		"app": {"type": "gitzup/test:dev", "config": {}}
+	}`).Apply(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{"web": build.ResourceStatusApplied, "app": build.ResourceStatusApplied})
+	if pulled := f.runtime.Pulled(); len(pulled) == 0 {
+		t.Error("expected the agent's pull policy to apply to resources without a pull policy")
+	}
+	for _, image := range f.runtime.Pulled() {
+		if image != fakeImage {
+			t.Errorf("unexpected image pulled: %s", image)
+		}
+	}
+}
+
+func TestIsStaleContainer(t *testing.T) {
+	spec := &build.ContainerSpec{Name: "req-web-apply", Labels: map[string]string{build.LabelManaged: "true", build.LabelAgent: "agent-1"}}
+	tests := []struct {
+		existing build.ContainerInfo
+		expected bool
+	}{
+		{existing: build.ContainerInfo{Labels: map[string]string{build.LabelManaged: "true", build.LabelAgent: "agent-2"}}, expected: true},
+		{existing: build.ContainerInfo{Running: true, Labels: map[string]string{build.LabelManaged: "true", build.LabelAgent: "agent-1"}}, expected: true},
+		{existing: build.ContainerInfo{Running: true, Labels: map[string]string{build.LabelManaged: "true", build.LabelAgent: "agent-2"}}},
+		{existing: build.ContainerInfo{Running: true, Labels: map[string]string{build.LabelManaged: "true"}}},
+		{existing: build.ContainerInfo{Labels: map[string]string{build.LabelAgent: "agent-1"}}},
+		{existing: build.ContainerInfo{}},
+	}
+	for _, test := range tests {
+		if stale := build.IsStaleContainer(test.existing, spec); stale != test.expected {
+			t.Errorf("expected container %+v to be stale: %v", test.existing, test.expected)
+		}
+	}
+}
+
+func TestApplyReplacesStaleContainers(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+	name := "testapplyreplacesstalecontainers-web-apply"
+	stale := f.runtime.AddContainer(build.ContainerInfo{Name: name, Labels: map[string]string{build.LabelManaged: "true"}})
+
+	result, err := f.request(`{
+		"web": {"type": "gitzup/test:dev", "config": {}}
+	}`).Apply(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{"web": build.ResourceStatusApplied})
+	if _, err := f.runtime.Inspect(context.Background(), stale); err == nil {
+		t.Error("expected stale container to be removed")
+	}
+
+	// containers not created by Gitzup are never replaced
+	foreign := f.runtime.AddContainer(build.ContainerInfo{Name: name})
+	if _, err := f.request(`{
+		"web": {"type": "gitzup/test:dev", "config": {}}
+	}`).Apply(context.Background()); err == nil {
+		t.Error("expected an error, since the container name is in use")
+	}
+	if _, err := f.runtime.Inspect(context.Background(), foreign); err != nil {
+		t.Errorf("expected foreign container to be kept: %s", err)
+	}
+}
@@ -0,0 +1,129 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	. "github.com/gitzup/agent/internal/logger"
+	"github.com/go-errors/errors"
+)
+
+type resourceResult struct {
+	resource *resourceImpl
+	err      error
+}
+
+// Invokes the given function for every resource in the request, respecting resource dependencies: a resource is only
+// processed once all the resources it depends on were processed successfully. Independent resources are processed
+// concurrently, up to the request's configured parallelism (and the type limiter's limit of each resource type, if any).
+//
+// When a resource fails, resources depending on it are skipped. If the request is configured to fail fast, the context
+// passed to resources still being processed is canceled, and no new resources are started. Similarly, once the given
+// context is done (eg. cancelled or timed out), no new resources are started, and the function returns the context's
+// error once running resources finish.
+func (req *requestImpl) processResources(parentCtx context.Context, process func(context.Context, *resourceImpl) error) error {
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	parallelism := req.options.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// count unprocessed dependencies for each resource, and collect the resources that can start right away
+	pending := make(map[string]int, len(req.sorted))
+	dependents := make(map[string][]*resourceImpl, len(req.sorted))
+	var ready []*resourceImpl
+	for _, res := range req.sorted {
+		pending[res.name] = len(res.dependsOn)
+		for _, dependency := range res.dependsOn {
+			dependents[dependency] = append(dependents[dependency], res)
+		}
+		if len(res.dependsOn) == 0 {
+			ready = append(ready, res)
+		}
+	}
+
+	results := make(chan resourceResult)
+	running := 0
+	processed := 0
+	aborted := false
+	var failures []string
+	for {
+		// stop starting resources once the build request was cancelled or timed out
+		if parentCtx.Err() != nil {
+			aborted = true
+		}
+
+		// start as many ready resources as we're allowed to
+		for !aborted && running < parallelism && len(ready) > 0 {
+			res := ready[0]
+			ready = ready[1:]
+			running++
+			go func(res *resourceImpl) {
+				resCtx := context.WithValue(ctx, "resource", res.Name())
+				results <- resourceResult{resource: res, err: req.processLimited(resCtx, res, process)}
+			}(res)
+		}
+		if running == 0 {
+			break
+		}
+
+		// wait for the next resource to finish
+		result := <-results
+		running--
+		processed++
+		if result.err != nil && parentCtx.Err() != nil {
+			aborted = true
+			From(ctx).WithField("resource", result.resource.Name()).WithError(result.err).Warn("Resource interrupted")
+			continue
+		} else if result.err != nil && aborted {
+			// failures after aborting are most likely caused by the cancellation itself; the original failure has
+			// already been reported
+			From(ctx).WithField("resource", result.resource.Name()).WithError(result.err).Warn("Resource aborted")
+			continue
+		} else if result.err != nil {
+			From(ctx).WithField("resource", result.resource.Name()).WithError(result.err).Error("Resource failed")
+			failures = append(failures, fmt.Sprintf("resource '%s' failed: %s", result.resource.Name(), result.err))
+			if req.options.FailFast {
+				aborted = true
+				cancel()
+			}
+			continue
+		}
+
+		// mark dependents whose dependencies are now all satisfied as ready
+		for _, dependent := range dependents[result.resource.name] {
+			pending[dependent.name]--
+			if pending[dependent.name] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if parentCtx.Err() != nil {
+		return errors.Wrap(parentCtx.Err(), 0)
+	}
+	if skipped := len(req.sorted) - processed; skipped > 0 {
+		From(ctx).Warnf("Skipped %d resource(s) due to failures", skipped)
+	}
+	if len(failures) == 1 {
+		return errors.New(failures[0])
+	} else if len(failures) > 1 {
+		return errors.Errorf("%d resources failed:\n\t- %s", len(failures), strings.Join(failures, "\n\t- "))
+	}
+	return nil
+}
+
+// Invokes the given function for the given resource once the type limiter (if any) allows it.
+func (req *requestImpl) processLimited(ctx context.Context, res *resourceImpl, process func(context.Context, *resourceImpl) error) error {
+	if req.options.TypeLimiter != nil {
+		release, err := req.options.TypeLimiter.acquire(ctx, res.Type())
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+	return process(ctx, res)
+}
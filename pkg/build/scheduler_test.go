@@ -0,0 +1,97 @@
+package build_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/gitzup/agent/pkg/build"
+)
+
+func TestApplyOrdersResourcesByDependencies(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+	f.options.Parallelism = 3
+
+	result, err := f.request(`{
+		"web": {"type": "gitzup/test:dev", "config": {}, "dependsOn": ["app"]},
+		"app": {"type": "gitzup/test:dev", "config": {"port": "${resources.net.outputs.port}"}},
+		"net": {"type": "gitzup/test:dev", "config": {"port": 8080, "delay": "50ms"}}
+	}`).Apply(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result.Status != build.StatusSucceeded {
+		t.Errorf("expected build request to succeed, got %s", result.Status)
+	}
+	if expected := []string{"net", "app", "web"}; !reflect.DeepEqual(f.applied, expected) {
+		t.Errorf("expected resources to be applied in order %v, got %v", expected, f.applied)
+	}
+}
+
+func TestApplyLimitsParallelism(t *testing.T) {
+	for _, parallelism := range []int{1, 2, 3} {
+		f := newFixture(t)
+		f.options.Parallelism = parallelism
+
+		_, err := f.request(`{
+			"aaa": {"type": "gitzup/test:dev", "config": {"delay": "200ms"}},
+			"bbb": {"type": "gitzup/test:dev", "config": {"delay": "200ms"}},
+			"ccc": {"type": "gitzup/test:dev", "config": {"delay": "200ms"}}
+		}`).Apply(context.Background())
+		f.close()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		} else if f.maxRunning != parallelism {
+			t.Errorf("expected %d resources to be applied concurrently, got %d", parallelism, f.maxRunning)
+		}
+	}
+}
+
+func TestApplyFailFast(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+	f.options.Parallelism = 3
+	f.options.FailFast = true
+
+	result, err := f.request(`{
+		"bad": {"type": "gitzup/test:dev", "config": {"delay": "50ms", "fail": true}},
+		"dep": {"type": "gitzup/test:dev", "config": {}, "dependsOn": ["bad"]},
+		"slow": {"type": "gitzup/test:dev", "config": {"delay": "10s"}}
+	}`).Apply(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if result.Status != build.StatusFailed {
+		t.Errorf("expected build request to fail, got %s", result.Status)
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{
+		"bad":  build.ResourceStatusFailed,
+		"dep":  build.ResourceStatusSkipped,
+		"slow": build.ResourceStatusCancelled,
+	})
+}
+
+func TestApplyContinuesPastFailures(t *testing.T) {
+	f := newFixture(t)
+	defer f.close()
+	f.options.Parallelism = 3
+	f.options.FailFast = false
+
+	result, err := f.request(`{
+		"bad": {"type": "gitzup/test:dev", "config": {"fail": true}},
+		"dep": {"type": "gitzup/test:dev", "config": {}, "dependsOn": ["bad"]},
+		"slow": {"type": "gitzup/test:dev", "config": {"delay": "100ms"}}
+	}`).Apply(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if result.Status != build.StatusFailed {
+		t.Errorf("expected build request to fail, got %s", result.Status)
+	}
+	f.assertStatuses(result, map[string]build.ResourceStatus{
+		"bad":  build.ResourceStatusFailed,
+		"dep":  build.ResourceStatusSkipped,
+		"slow": build.ResourceStatusApplied,
+	})
+}
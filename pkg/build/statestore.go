@@ -0,0 +1,102 @@
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+// Persisted record of a single resource, as of its last successful apply.
+type StateRecord struct {
+	Project   string                 `json:"project"`
+	Resource  string                 `json:"resource"`
+	Type      string                 `json:"type"`
+	RequestId string                 `json:"requestId"`
+	AppliedAt time.Time              `json:"appliedAt"`
+	Config    interface{}            `json:"config,omitempty"`
+	State     interface{}            `json:"state,omitempty"`
+	Outputs   map[string]interface{} `json:"outputs,omitempty"`
+}
+
+// Persistent storage of resource state records across build requests. Records are keyed by project and resource name.
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Returns the record of the given resource in the given project, or nil if no such record exists.
+	Get(project string, resource string) (*StateRecord, error)
+
+	// Saves the given record, replacing any existing record of the same project & resource.
+	Put(record *StateRecord) error
+
+	// Releases any resources held by this store.
+	Close() error
+}
+
+// State store keeping each record as a JSON file, under "<dir>/<project>/<resource>.json".
+type fileStateStore struct {
+	dir   string
+	mutex sync.RWMutex
+}
+
+// Creates a state store keeping records as JSON files under the given directory.
+func NewFileStateStore(dir string) (StateStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed creating state directory '%s'", dir), 0)
+	}
+	return &fileStateStore{dir: dir}, nil
+}
+
+func (store *fileStateStore) recordPath(project string, resource string) string {
+	return path.Join(store.dir, project, resource+".json")
+}
+
+func (store *fileStateStore) Get(project string, resource string) (*StateRecord, error) {
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	b, err := ioutil.ReadFile(store.recordPath(project, resource))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed reading state of resource '%s'", resource), 0)
+	}
+
+	var record StateRecord
+	if err := json.Unmarshal(b, &record); err != nil {
+		return nil, errors.WrapPrefix(err, fmt.Sprintf("failed parsing state of resource '%s'", resource), 0)
+	}
+	return &record, nil
+}
+
+func (store *fileStateStore) Put(record *StateRecord) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	b, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed serializing state of resource '%s'", record.Resource), 0)
+	}
+
+	// write to a temporary file first, and then rename it, so a crash never leaves a partially-written record
+	recordPath := store.recordPath(record.Project, record.Resource)
+	if err := os.MkdirAll(path.Dir(recordPath), 0755); err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed creating state directory of project '%s'", record.Project), 0)
+	}
+	tempPath := recordPath + ".tmp"
+	if err := ioutil.WriteFile(tempPath, b, 0644); err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed writing state of resource '%s'", record.Resource), 0)
+	}
+	if err := os.Rename(tempPath, recordPath); err != nil {
+		return errors.WrapPrefix(err, fmt.Sprintf("failed writing state of resource '%s'", record.Resource), 0)
+	}
+	return nil
+}
+
+func (store *fileStateStore) Close() error {
+	return nil
+}
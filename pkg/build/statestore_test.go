@@ -0,0 +1,118 @@
+package build_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gitzup/agent/pkg/build"
+)
+
+// Runs the given test against each state store implementation; the given function opens a store (or reopens the same
+// store, if called again).
+func testStateStores(t *testing.T, test func(t *testing.T, open func() build.StateStore)) {
+	stores := map[string]func(dir string) (build.StateStore, error){
+		"file": func(dir string) (build.StateStore, error) {
+			return build.NewFileStateStore(filepath.Join(dir, "state"))
+		},
+		"bolt": func(dir string) (build.StateStore, error) {
+			return build.NewBoltStateStore(filepath.Join(dir, "state.db"))
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "gitzup-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			test(t, func() build.StateStore {
+				store, err := newStore(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return store
+			})
+		})
+	}
+}
+
+func newStateRecord(project string, resource string, requestId string) *build.StateRecord {
+	return &build.StateRecord{
+		Project:   project,
+		Resource:  resource,
+		Type:      "gitzup/test:dev",
+		RequestId: requestId,
+		AppliedAt: time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC),
+		Config:    map[string]interface{}{"port": "8080"},
+		State:     map[string]interface{}{"id": "abc"},
+		Outputs:   map[string]interface{}{"url": "http://web:8080"},
+	}
+}
+
+func TestStateStoreGetMissingRecord(t *testing.T) {
+	testStateStores(t, func(t *testing.T, open func() build.StateStore) {
+		store := open()
+		defer store.Close()
+		if record, err := store.Get("proj", "web"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		} else if record != nil {
+			t.Errorf("expected no record, got %+v", record)
+		}
+	})
+}
+
+func TestStateStorePutAndGet(t *testing.T) {
+	testStateStores(t, func(t *testing.T, open func() build.StateStore) {
+		store := open()
+		defer store.Close()
+
+		for _, record := range []*build.StateRecord{
+			newStateRecord("proj", "web", "req1"),
+			newStateRecord("proj", "web", "req2"),
+			newStateRecord("other", "web", "req3"),
+		} {
+			if err := store.Put(record); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+
+		expected := map[string]*build.StateRecord{
+			"proj":  newStateRecord("proj", "web", "req2"),
+			"other": newStateRecord("other", "web", "req3"),
+		}
+		for project, expectedRecord := range expected {
+			record, err := store.Get(project, "web")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if !reflect.DeepEqual(record, expectedRecord) {
+				t.Errorf("expected record %+v in project '%s', got %+v", expectedRecord, project, record)
+			}
+		}
+		if record, err := store.Get("proj", "app"); err != nil || record != nil {
+			t.Errorf("expected no record of other resources, got %+v (%v)", record, err)
+		}
+	})
+}
+
+func TestStateStorePersistsRecords(t *testing.T) {
+	testStateStores(t, func(t *testing.T, open func() build.StateStore) {
+		store := open()
+		if err := store.Put(newStateRecord("proj", "web", "req1")); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		} else if err := store.Close(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		store = open()
+		defer store.Close()
+		if record, err := store.Get("proj", "web"); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		} else if expected := newStateRecord("proj", "web", "req1"); !reflect.DeepEqual(record, expected) {
+			t.Errorf("expected record %+v after reopening the store, got %+v", expected, record)
+		}
+	})
+}